@@ -0,0 +1,163 @@
+package bloom
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultCapacity          = 1000000
+	DefaultFalsePositiveRate = 0.01
+
+	// maxBits is the size limit of a redis string, 512MB.
+	maxBits = 1 << 32
+)
+
+var (
+	add = redis.NewScript(`
+local added = 0
+for i = 1, #ARGV do
+	if redis.call("SETBIT", KEYS[1], ARGV[i], 1) == 0 then
+		added = 1
+	end
+end
+return added`)
+
+	contains = redis.NewScript(`
+for i = 1, #ARGV do
+	if redis.call("GETBIT", KEYS[1], ARGV[i]) == 0 then
+		return 0
+	end
+end
+return 1`)
+)
+
+type (
+	Option struct {
+		// Key is the redis bitmap holding the filter.
+		Key string
+		// Capacity is the expected number of items, the false positive rate
+		// grows past FalsePositiveRate once it is exceeded.
+		Capacity          uint64
+		FalsePositiveRate float64
+	}
+
+	// Filter is a Bloom filter, it never misses an added item but can report
+	// an item that was never added.
+	Filter interface {
+		// AddWithContext reports whether item was definitely not in the filter before.
+		AddWithContext(ctx context.Context, item string) (bool, error)
+		Add(item string) (bool, error)
+		MightContainWithContext(ctx context.Context, item string) (bool, error)
+		MightContain(item string) (bool, error)
+	}
+
+	filter struct {
+		option *Option
+		client redis.UniversalClient
+		bits   uint64
+		hashes uint64
+	}
+)
+
+func getOption(option *Option) error {
+	if option.Key == "" {
+		return errors.New("bloom filter key is required")
+	}
+
+	if option.Capacity == 0 {
+		option.Capacity = DefaultCapacity
+	}
+
+	if option.FalsePositiveRate == 0 {
+		option.FalsePositiveRate = DefaultFalsePositiveRate
+	}
+
+	if option.FalsePositiveRate <= 0 || option.FalsePositiveRate >= 1 {
+		return errors.New("false positive rate must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// New creates a Filter stored in a bitmap of one of the redis backed caches.
+func New(client cache.Cache, option *Option) (Filter, error) {
+	instance, ok := client.(cache.Instance)
+	if !ok {
+		return nil, errors.New("cache client is not backed by redis")
+	}
+
+	if err := getOption(option); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	bits, hashes := size(option.Capacity, option.FalsePositiveRate)
+	return &filter{option: option, client: instance.Instance(), bits: bits, hashes: hashes}, nil
+}
+
+func (f *filter) AddWithContext(ctx context.Context, item string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	added, err := add.Run(f.client, []string{f.option.Key}, f.offsets(item)...).Int64()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to add to bloom filter %s!", f.option.Key)
+	}
+
+	return added == 1, nil
+}
+
+func (f *filter) Add(item string) (bool, error) {
+	return f.AddWithContext(context.Background(), item)
+}
+
+func (f *filter) MightContainWithContext(ctx context.Context, item string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	found, err := contains.Run(f.client, []string{f.option.Key}, f.offsets(item)...).Int64()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to check bloom filter %s!", f.option.Key)
+	}
+
+	return found == 1, nil
+}
+
+func (f *filter) MightContain(item string) (bool, error) {
+	return f.MightContainWithContext(context.Background(), item)
+}
+
+// offsets derives the bits of item by double hashing.
+func (f *filter) offsets(item string) []interface{} {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(item))
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32|1
+
+	offsets := make([]interface{}, f.hashes)
+	for i := uint64(0); i < f.hashes; i++ {
+		offsets[i] = (h1 + i*h2) % f.bits
+	}
+
+	return offsets
+}
+
+// size returns the optimal number of bits and hash functions for n items at
+// false positive rate p.
+func size(n uint64, p float64) (uint64, uint64) {
+	bits := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	if bits > maxBits {
+		bits = maxBits
+	}
+
+	hashes := math.Max(1, math.Round(bits/float64(n)*math.Ln2))
+	return uint64(bits), uint64(hashes)
+}
@@ -0,0 +1,135 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jajotz/utilities-golang/cache/memory"
+	"github.com/jajotz/utilities-golang/cache/redis"
+	"github.com/jajotz/utilities-golang/cache/redistest"
+)
+
+func newFilter(t *testing.T, option *Option) (Filter, *redistest.Server) {
+	server := redistest.Run(t)
+
+	client, err := redis.New(&redis.Option{Address: server.Addr()})
+	if err != nil {
+		server.Close()
+		t.Fatal("should not error ", err)
+	}
+
+	f, err := New(client, option)
+	if err != nil {
+		server.Close()
+		t.Fatal("should not error ", err)
+	}
+
+	return f, server
+}
+
+func Test_size_matches_false_positive_rate(t *testing.T) {
+	bits, hashes := size(1000000, 0.01)
+
+	if bits != 9585059 {
+		t.Errorf("expected 9585059 bits, got %d", bits)
+	}
+
+	if hashes != 7 {
+		t.Errorf("expected 7 hashes, got %d", hashes)
+	}
+}
+
+func Test_offsets_are_stable_and_in_range(t *testing.T) {
+	f := &filter{option: &Option{Key: "bloom"}, bits: 1000, hashes: 5}
+
+	first, second := f.offsets("order-1"), f.offsets("order-1")
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("offset %d changed between calls", i)
+		}
+
+		if first[i].(uint64) >= 1000 {
+			t.Errorf("offset %d out of range: %d", i, first[i])
+		}
+	}
+}
+
+func Test_New_requires_redis(t *testing.T) {
+	if _, err := New(memory.New(), &Option{Key: "bloom"}); err == nil {
+		t.Error("should error on a cache not backed by redis")
+	}
+}
+
+func Test_Add_and_MightContain(t *testing.T) {
+	f, server := newFilter(t, &Option{Key: "bloom", Capacity: 100})
+	defer server.Close()
+
+	added, err := f.Add("order-1")
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if !added {
+		t.Error("first add should report a new item")
+	}
+
+	if added, err := f.Add("order-1"); err != nil || added {
+		t.Errorf("second add should report a known item, got %v %v", added, err)
+	}
+
+	if found, err := f.MightContain("order-1"); err != nil || !found {
+		t.Errorf("added item should be found, got %v %v", found, err)
+	}
+
+	if found, err := f.MightContain("order-2"); err != nil || found {
+		t.Errorf("unknown item should not be found, got %v %v", found, err)
+	}
+
+	if !server.Exists("bloom") {
+		t.Error("expected the filter bitmap to be stored")
+	}
+}
+
+func Test_false_positive_rate_is_bounded(t *testing.T) {
+	f, server := newFilter(t, &Option{Key: "bloom", Capacity: 200, FalsePositiveRate: 0.01})
+	defer server.Close()
+
+	for i := 0; i < 200; i++ {
+		if _, err := f.Add(fmt.Sprintf("added-%d", i)); err != nil {
+			t.Fatal("should not error ", err)
+		}
+	}
+
+	positives := 0
+	for i := 0; i < 1000; i++ {
+		found, err := f.MightContain(fmt.Sprintf("unknown-%d", i))
+		if err != nil {
+			t.Fatal("should not error ", err)
+		}
+
+		if found {
+			positives++
+		}
+	}
+
+	if positives > 50 {
+		t.Errorf("expected about 1%% false positives, got %d in 1000", positives)
+	}
+}
+
+func Test_AddWithContext_cancelled(t *testing.T) {
+	f, server := newFilter(t, &Option{Key: "bloom"})
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := f.AddWithContext(ctx, "order-1"); err == nil {
+		t.Error("should error on a cancelled context")
+	}
+
+	if _, err := f.MightContainWithContext(ctx, "order-1"); err == nil {
+		t.Error("should error on a cancelled context")
+	}
+}
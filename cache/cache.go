@@ -1,48 +1,104 @@
 package cache
 
 import (
+	"context"
 	"time"
+
 	"github.com/jajotz/utilities-golang/util"
 
 	"github.com/go-redis/redis"
 )
 
 type (
+	// Result is returned by the commands queued on a Pipe, it only holds the
+	// reply once the pipe has been executed.
+	Result interface {
+		Err() error
+	}
+	IntResult interface {
+		Result
+		Val() int64
+	}
+	BoolResult interface {
+		Result
+		Val() bool
+	}
+	StringMapResult interface {
+		Result
+		Val() map[string]string
+	}
+	ValueResult interface {
+		Result
+		Scan(object interface{}) error
+	}
+
 	Pipe interface {
 		Set(key string, value interface{}) error
 		SetWithExpiration(key string, value interface{}, expired time.Duration) error
-		Get(key string, object interface{}) error
+		Get(key string) ValueResult
+		HSet(key, field string, value interface{}) error
+		HMSet(key string, value map[string]interface{}) error
+		HGetAll(key string) StringMapResult
+		Del(keys ...string) IntResult
+		Expire(key string, expiration time.Duration) BoolResult
+		Incr(key string) IntResult
+		ZAdd(key string, members ...redis.Z) IntResult
+		ExecWithContext(ctx context.Context) error
 		Exec() error
 	}
 	Cache interface {
 		util.Ping
+		PingWithContext(context.Context) error
+
+		SetWithExpirationWithContext(context.Context, string, interface{}, time.Duration) error
 		SetWithExpiration(string, interface{}, time.Duration) error
+		SetWithContext(context.Context, string, interface{}) error
 		Set(string, interface{}) error
+		GetWithContext(context.Context, string, interface{}) error
 		Get(string, interface{}) error
 
+		SetZSetWithExpirationWithContext(context.Context, string, time.Duration, ...redis.Z) error
 		SetZSetWithExpiration(string, time.Duration, ...redis.Z) error
+		SetZSetWithContext(context.Context, string, ...redis.Z) error
 		SetZSet(string, ...redis.Z) error
+		GetZSetWithContext(context.Context, string) ([]redis.Z, error)
 		GetZSet(string) ([]redis.Z, error)
 
+		HMSetWithExpirationWithContext(ctx context.Context, key string, value map[string]interface{}, ttl time.Duration) error
 		HMSetWithExpiration(key string, value map[string]interface{}, ttl time.Duration) error
+		HMSetWithContext(ctx context.Context, key string, value map[string]interface{}) error
 		HMSet(key string, value map[string]interface{}) error
+		HSetWithExpirationWithContext(ctx context.Context, key, field string, value interface{}, ttl time.Duration) error
 		HSetWithExpiration(key, field string, value interface{}, ttl time.Duration) error
+		HSetWithContext(ctx context.Context, key, field string, value interface{}) error
 		HSet(key, field string, value interface{}) error
+		HMGetWithContext(ctx context.Context, key string, fields ...string) ([]interface{}, error)
 		HMGet(key string, fields ...string) ([]interface{}, error)
+		HGetAllWithContext(ctx context.Context, key string) (map[string]string, error)
 		HGetAll(key string) (map[string]string, error)
+		HGetWithContext(ctx context.Context, key, field string, response interface{}) error
 		HGet(key, field string, response interface{}) error
 
+		MGetWithContext(context.Context, []string) ([]interface{}, error)
 		MGet(key []string) ([]interface{}, error)
 
+		KeysWithContext(context.Context, string) ([]string, error)
 		Keys(string) ([]string, error)
 
+		RemoveWithContext(context.Context, string) error
 		Remove(string) error
+		RemoveByPatternWithContext(context.Context, string, int64) error
 		RemoveByPattern(string, int64) error
+		FlushDatabaseWithContext(context.Context) error
 		FlushDatabase() error
+		FlushAllWithContext(context.Context) error
 		FlushAll() error
 		Close() error
 
+		PipelineWithContext(context.Context) Pipe
 		Pipeline() Pipe
+		TxPipelineWithContext(context.Context) Pipe
+		TxPipeline() Pipe
 		Client() Cache
 	}
 
@@ -53,4 +109,126 @@ type (
 		Client() Cache
 		Close() error
 	}
+
+	// Decoder is implemented by caches that serialize values with a codec,
+	// it unmarshals the raw values returned by MGet and HMGet.
+	Decoder interface {
+		Decode(value interface{}, object interface{}) error
+	}
+
+	// Instance is implemented by the caches backed by a go-redis client, it gives
+	// companion packages access to commands that are not part of Cache.
+	Instance interface {
+		Instance() redis.UniversalClient
+	}
+
+	// Scanner is implemented by the caches that can iterate keys with SCAN
+	// instead of the blocking KEYS, on a cluster every master is scanned.
+	Scanner interface {
+		ScanKeysWithContext(ctx context.Context, pattern string, count int64) ([]string, error)
+		ScanKeys(pattern string, count int64) ([]string, error)
+		UnlinkByPatternWithContext(ctx context.Context, pattern string, count int64) (int64, error)
+		UnlinkByPattern(pattern string, count int64) (int64, error)
+	}
+
+	// Counter is implemented by the caches supporting atomic counters, HyperLogLog
+	// and bitmaps. A positive ttl is only applied when the write creates the key.
+	Counter interface {
+		IncrWithContext(ctx context.Context, key string, ttl time.Duration) (int64, error)
+		Incr(key string, ttl time.Duration) (int64, error)
+		DecrWithContext(ctx context.Context, key string, ttl time.Duration) (int64, error)
+		Decr(key string, ttl time.Duration) (int64, error)
+		IncrByWithContext(ctx context.Context, key string, value int64, ttl time.Duration) (int64, error)
+		IncrBy(key string, value int64, ttl time.Duration) (int64, error)
+		HIncrByWithContext(ctx context.Context, key, field string, value int64, ttl time.Duration) (int64, error)
+		HIncrBy(key, field string, value int64, ttl time.Duration) (int64, error)
+
+		PFAddWithContext(ctx context.Context, key string, ttl time.Duration, elements ...interface{}) (bool, error)
+		PFAdd(key string, ttl time.Duration, elements ...interface{}) (bool, error)
+		PFCountWithContext(ctx context.Context, keys ...string) (int64, error)
+		PFCount(keys ...string) (int64, error)
+
+		SetBitWithContext(ctx context.Context, key string, offset int64, value int, ttl time.Duration) (int64, error)
+		SetBit(key string, offset int64, value int, ttl time.Duration) (int64, error)
+		GetBitWithContext(ctx context.Context, key string, offset int64) (int64, error)
+		GetBit(key string, offset int64) (int64, error)
+		BitCountWithContext(ctx context.Context, key string) (int64, error)
+		BitCount(key string) (int64, error)
+	}
+
+	// SortedSet is implemented by the caches supporting incremental sorted set
+	// updates and ranged reads. Ranks start at 0, negative ranks count from the
+	// end, and a missing member is reported as redis.Nil.
+	SortedSet interface {
+		ZAddWithContext(ctx context.Context, key string, members ...redis.Z) (int64, error)
+		ZAdd(key string, members ...redis.Z) (int64, error)
+		ZIncrByWithContext(ctx context.Context, key string, increment float64, member string) (float64, error)
+		ZIncrBy(key string, increment float64, member string) (float64, error)
+		ZRemWithContext(ctx context.Context, key string, members ...interface{}) (int64, error)
+		ZRem(key string, members ...interface{}) (int64, error)
+		ZRemRangeByRankWithContext(ctx context.Context, key string, start, stop int64) (int64, error)
+		ZRemRangeByRank(key string, start, stop int64) (int64, error)
+
+		ZRangeWithContext(ctx context.Context, key string, start, stop int64) ([]redis.Z, error)
+		ZRange(key string, start, stop int64) ([]redis.Z, error)
+		ZRevRangeWithContext(ctx context.Context, key string, start, stop int64) ([]redis.Z, error)
+		ZRevRange(key string, start, stop int64) ([]redis.Z, error)
+		ZRangeByScoreWithContext(ctx context.Context, key string, opt redis.ZRangeBy) ([]redis.Z, error)
+		ZRangeByScore(key string, opt redis.ZRangeBy) ([]redis.Z, error)
+		ZRevRangeByScoreWithContext(ctx context.Context, key string, opt redis.ZRangeBy) ([]redis.Z, error)
+		ZRevRangeByScore(key string, opt redis.ZRangeBy) ([]redis.Z, error)
+
+		ZRankWithContext(ctx context.Context, key, member string) (int64, error)
+		ZRank(key, member string) (int64, error)
+		ZRevRankWithContext(ctx context.Context, key, member string) (int64, error)
+		ZRevRank(key, member string) (int64, error)
+		ZScoreWithContext(ctx context.Context, key, member string) (float64, error)
+		ZScore(key, member string) (float64, error)
+		ZCardWithContext(ctx context.Context, key string) (int64, error)
+		ZCard(key string) (int64, error)
+	}
+
+	Message struct {
+		Channel string
+		// Pattern is set when the message was received through PSubscribe.
+		Pattern string
+		Payload string
+	}
+
+	// Subscription delivers the messages published on its channels until it is closed.
+	Subscription interface {
+		Messages() <-chan *Message
+		Close() error
+	}
+
+	// PubSub is implemented by the caches that can broadcast messages.
+	PubSub interface {
+		PublishWithContext(ctx context.Context, channel string, message interface{}) error
+		Publish(channel string, message interface{}) error
+		Subscribe(channels ...string) (Subscription, error)
+		PSubscribe(patterns ...string) (Subscription, error)
+	}
+
+	// Command describes a redis command reported to a Hook, a lookup of a missing
+	// key is a miss and not an error.
+	Command struct {
+		Name     string
+		Key      string
+		Duration time.Duration
+		// Read is set for the commands looking up values, Hit reports whether they were found.
+		Read bool
+		Hit  bool
+		Err  error
+	}
+
+	// Hook observes the commands processed by the redis caches. The context
+	// returned by the Before methods is passed to the matching After methods,
+	// the commands of a pipeline share the duration of the whole round trip.
+	// The messages received by a Subscription are not commands and are not reported.
+	Hook interface {
+		BeforeProcess(ctx context.Context, cmd *Command) context.Context
+		AfterProcess(ctx context.Context, cmd *Command)
+		BeforeProcessPipeline(ctx context.Context, cmds []*Command) context.Context
+		AfterProcessPipeline(ctx context.Context, cmds []*Command)
+	}
 )
@@ -0,0 +1,332 @@
+// Package cachetest is the conformance suite shared by the cache implementations.
+package cachetest
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const ttl = 100 * time.Millisecond
+
+type (
+	Option struct {
+		// New creates the cache under test, its database is flushed before every case.
+		New func() (cache.Cache, error)
+		// Sleep lets the expirations of the cache elapse, defaults to time.Sleep.
+		Sleep func(time.Duration)
+	}
+
+	value struct {
+		value string
+	}
+)
+
+func (v *value) MarshalBinary() ([]byte, error) {
+	return []byte(v.value), nil
+}
+
+func (v *value) UnmarshalBinary(data []byte) error {
+	v.value = string(data)
+	return nil
+}
+
+// Run checks that the cache created by option behaves like redis.
+func Run(t *testing.T, option *Option) {
+	if option.Sleep == nil {
+		option.Sleep = time.Sleep
+	}
+
+	cases := []struct {
+		name string
+		test func(t *testing.T, c cache.Cache, option *Option)
+	}{
+		{name: "Get_returns_value", test: testGet},
+		{name: "Get_returns_redis_nil_when_missing", test: testGetMissing},
+		{name: "SetWithExpiration_expires", test: testSetWithExpiration},
+		{name: "HMSetWithExpiration_expires", test: testHMSetWithExpiration},
+		{name: "HMSetWithExpiration_writes_many_fields", test: testHMSetManyFields},
+		{name: "HMSetWithExpiration_rejects_empty_fields", test: testHMSetEmpty},
+		{name: "HMSetWithExpiration_keeps_sub_millisecond_ttl", test: testHMSetSubMillisecond},
+		{name: "HSetWithExpiration_expires", test: testHSetWithExpiration},
+		{name: "PFAdd_adds_many_elements", test: testPFAddManyElements},
+		{name: "SetZSet_replaces_members", test: testSetZSet},
+		{name: "SetZSetWithExpiration_expires", test: testSetZSetWithExpiration},
+		{name: "SetZSet_rejects_empty_members", test: testSetZSetEmpty},
+		{name: "SetZSetWithExpiration_keeps_sub_millisecond_ttl", test: testSetZSetSubMillisecond},
+		{name: "RemoveByPattern_removes_matching_keys", test: testRemoveByPattern},
+		{name: "Pipeline_applies_on_exec", test: testPipeline},
+		{name: "TxPipeline_applies_on_exec", test: testTxPipeline},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := option.New()
+			if err != nil {
+				t.Fatal("should not error ", err)
+			}
+			defer c.Close()
+
+			if err := c.FlushDatabase(); err != nil {
+				t.Fatal("should not error ", err)
+			}
+
+			tc.test(t, c, option)
+		})
+	}
+}
+
+func testGet(t *testing.T, c cache.Cache, option *Option) {
+	if err := c.Set("key", &value{value: "value"}); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	object := value{}
+	if err := c.Get("key", &object); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if object.value != "value" {
+		t.Errorf("expected value, got %s", object.value)
+	}
+}
+
+func testGetMissing(t *testing.T, c cache.Cache, option *Option) {
+	if err := c.Get("missing", &value{}); errors.Cause(err) != redis.Nil {
+		t.Errorf("expected redis.Nil, got %v", err)
+	}
+}
+
+func testSetWithExpiration(t *testing.T, c cache.Cache, option *Option) {
+	if err := c.SetWithExpiration("key", &value{value: "value"}, ttl); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	option.Sleep(2 * ttl)
+
+	if err := c.Get("key", &value{}); errors.Cause(err) != redis.Nil {
+		t.Errorf("expected key to expire, got %v", err)
+	}
+}
+
+func testHMSetWithExpiration(t *testing.T, c cache.Cache, option *Option) {
+	if err := c.HMSetWithExpiration("hash", map[string]interface{}{"a": "1", "b": "2"}, ttl); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	fields, err := c.HGetAll("hash")
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if len(fields) != 2 || fields["a"] != "1" || fields["b"] != "2" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+
+	option.Sleep(2 * ttl)
+
+	if fields, err := c.HGetAll("hash"); err != nil || len(fields) != 0 {
+		t.Errorf("expected hash to expire, got %v %v", fields, err)
+	}
+}
+
+func testHMSetManyFields(t *testing.T, c cache.Cache, option *Option) {
+	value := make(map[string]interface{}, 10000)
+	for i := 0; i < 10000; i++ {
+		value[strconv.Itoa(i)] = "v"
+	}
+
+	if err := c.HMSetWithExpiration("hash", value, time.Minute); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if fields, err := c.HGetAll("hash"); err != nil || len(fields) != len(value) {
+		t.Errorf("expected %d fields, got %d %v", len(value), len(fields), err)
+	}
+}
+
+func testHMSetEmpty(t *testing.T, c cache.Cache, option *Option) {
+	_ = c.HMSet("hash", map[string]interface{}{"a": "1"})
+	if err := c.HMSetWithExpiration("hash", map[string]interface{}{}, time.Minute); err == nil {
+		t.Error("should error on empty fields")
+	}
+
+	if fields, err := c.HGetAll("hash"); err != nil || len(fields) != 1 {
+		t.Errorf("expected hash to be kept, got %v %v", fields, err)
+	}
+}
+
+func testHMSetSubMillisecond(t *testing.T, c cache.Cache, option *Option) {
+	if err := c.HMSetWithExpiration("hash", map[string]interface{}{"a": "1"}, 900*time.Microsecond); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if fields, err := c.HGetAll("hash"); err != nil || len(fields) != 1 {
+		t.Errorf("expected hash to be kept, got %v %v", fields, err)
+	}
+
+	option.Sleep(2 * time.Millisecond)
+
+	if fields, err := c.HGetAll("hash"); err != nil || len(fields) != 0 {
+		t.Errorf("expected hash to expire, got %v %v", fields, err)
+	}
+}
+
+func testPFAddManyElements(t *testing.T, c cache.Cache, option *Option) {
+	counter, ok := c.(cache.Counter)
+	if !ok {
+		t.Skip("cache does not support counters")
+	}
+
+	elements := make([]interface{}, 10000)
+	for i := range elements {
+		elements[i] = strconv.Itoa(i)
+	}
+
+	if changed, err := counter.PFAdd("hll", time.Minute, elements...); err != nil || !changed {
+		t.Fatalf("expected estimate to change, got %v %v", changed, err)
+	}
+
+	// - only the last batch holds a new element
+	elements[len(elements)-1] = "new"
+	if changed, err := counter.PFAdd("hll", time.Minute, elements...); err != nil || !changed {
+		t.Errorf("expected estimate to change, got %v %v", changed, err)
+	}
+
+	count, err := counter.PFCount("hll")
+	if err != nil || count < 9800 || count > 10200 {
+		t.Errorf("unexpected estimate %d %v", count, err)
+	}
+}
+
+func testHSetWithExpiration(t *testing.T, c cache.Cache, option *Option) {
+	if err := c.HSetWithExpiration("hash", "field", &value{value: "value"}, ttl); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	object := value{}
+	if err := c.HGet("hash", "field", &object); err != nil || object.value != "value" {
+		t.Errorf("expected value, got %s %v", object.value, err)
+	}
+
+	option.Sleep(2 * ttl)
+
+	if err := c.HGet("hash", "field", &value{}); errors.Cause(err) != redis.Nil {
+		t.Errorf("expected hash to expire, got %v", err)
+	}
+}
+
+func testSetZSet(t *testing.T, c cache.Cache, option *Option) {
+	_ = c.SetZSet("zset", redis.Z{Score: 1, Member: "stale"})
+	if err := c.SetZSet("zset", redis.Z{Score: 2, Member: "b"}, redis.Z{Score: 1, Member: "a"}); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	members, err := c.GetZSet("zset")
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if len(members) != 2 || members[0].Member != "a" || members[1].Member != "b" {
+		t.Errorf("unexpected members %v", members)
+	}
+}
+
+func testSetZSetWithExpiration(t *testing.T, c cache.Cache, option *Option) {
+	if err := c.SetZSetWithExpiration("zset", ttl, redis.Z{Score: 1, Member: "a"}); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	option.Sleep(2 * ttl)
+
+	if members, err := c.GetZSet("zset"); err == nil {
+		t.Errorf("expected sorted set to expire, got %v", members)
+	}
+}
+
+func testSetZSetEmpty(t *testing.T, c cache.Cache, option *Option) {
+	_ = c.SetZSet("zset", redis.Z{Score: 1, Member: "a"})
+	if err := c.SetZSet("zset"); err == nil {
+		t.Error("should error on empty members")
+	}
+
+	if err := c.SetZSetWithExpiration("zset", time.Minute); err == nil {
+		t.Error("should error on empty members")
+	}
+
+	if members, err := c.GetZSet("zset"); err != nil || len(members) != 1 {
+		t.Errorf("expected sorted set to be kept, got %v %v", members, err)
+	}
+}
+
+func testSetZSetSubMillisecond(t *testing.T, c cache.Cache, option *Option) {
+	if err := c.SetZSetWithExpiration("zset", 900*time.Microsecond, redis.Z{Score: 1, Member: "a"}); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if members, err := c.GetZSet("zset"); err != nil || len(members) != 1 {
+		t.Errorf("expected sorted set to be kept, got %v %v", members, err)
+	}
+
+	option.Sleep(2 * time.Millisecond)
+
+	if members, err := c.GetZSet("zset"); err == nil {
+		t.Errorf("expected sorted set to expire, got %v", members)
+	}
+}
+
+func testRemoveByPattern(t *testing.T, c cache.Cache, option *Option) {
+	_ = c.Set("user:1", "1")
+	_ = c.Set("user:2", "2")
+	_ = c.Set("order:1", "1")
+
+	if err := c.RemoveByPattern("user:*", 10); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	keys, err := c.Keys("*")
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if len(keys) != 1 || keys[0] != "order:1" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
+
+func testPipeline(t *testing.T, c cache.Cache, option *Option) {
+	testPipe(t, c, c.Pipeline())
+}
+
+func testTxPipeline(t *testing.T, c cache.Cache, option *Option) {
+	testPipe(t, c, c.TxPipeline())
+}
+
+func testPipe(t *testing.T, c cache.Cache, pipe cache.Pipe) {
+	_ = pipe.Set("key", &value{value: "value"})
+	incr := pipe.Incr("counter")
+	get := pipe.Get("key")
+
+	if err := c.Get("key", &value{}); errors.Cause(err) != redis.Nil {
+		t.Errorf("pipe should not apply before exec, got %v", err)
+	}
+
+	if err := pipe.Exec(); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if incr.Val() != 1 {
+		t.Errorf("expected counter 1, got %d", incr.Val())
+	}
+
+	object := value{}
+	if err := get.Scan(&object); err != nil || object.value != "value" {
+		t.Errorf("expected value, got %s %v", object.value, err)
+	}
+}
@@ -0,0 +1,118 @@
+package codec
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type (
+	// Codec serializes the values written to and read from the cache.
+	Codec interface {
+		Marshal(interface{}) ([]byte, error)
+		Unmarshal([]byte, interface{}) error
+	}
+
+	binaryCodec  struct{}
+	jsonCodec    struct{}
+	gobCodec     struct{}
+	msgpackCodec struct{}
+)
+
+var (
+	// Binary keeps the go-redis behaviour: scalars are written as strings, other
+	// values must implement encoding.BinaryMarshaler and encoding.BinaryUnmarshaler.
+	Binary Codec = binaryCodec{}
+	JSON   Codec = jsonCodec{}
+	Gob    Codec = gobCodec{}
+	// Msgpack encodes with github.com/vmihailenco/msgpack.
+	Msgpack Codec = msgpackCodec{}
+)
+
+func (binaryCodec) Marshal(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(nil, v, 10), nil
+	case uint:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(nil, v, 10), nil
+	case float32:
+		return strconv.AppendFloat(nil, float64(v), 'f', -1, 64), nil
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	case encoding.BinaryMarshaler:
+		return v.MarshalBinary()
+	default:
+		return nil, fmt.Errorf("redis: can't marshal %T (implement encoding.BinaryMarshaler)", v)
+	}
+}
+
+func (binaryCodec) Unmarshal(data []byte, object interface{}) error {
+	unmarshaler, ok := object.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return errors.New("redis: can't unmarshal (implement encoding.BinaryUnmarshaler)")
+	}
+
+	return unmarshaler.UnmarshalBinary(data)
+}
+
+func (jsonCodec) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonCodec) Unmarshal(data []byte, object interface{}) error {
+	return json.Unmarshal(data, object)
+}
+
+func (gobCodec) Marshal(value interface{}) ([]byte, error) {
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, object interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(object)
+}
+
+func (msgpackCodec) Marshal(value interface{}) ([]byte, error) {
+	return msgpack.Marshal(value)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, object interface{}) error {
+	return msgpack.Unmarshal(data, object)
+}
@@ -0,0 +1,95 @@
+package codec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type testStruct struct {
+	Name  string
+	Count int
+}
+
+func Test_structured_codecs_roundtrip(t *testing.T) {
+	for name, c := range map[string]Codec{"json": JSON, "gob": Gob, "msgpack": Msgpack} {
+		data, err := c.Marshal(testStruct{Name: "name", Count: 2})
+		if err != nil {
+			t.Errorf("%s should not error %v", name, err)
+			continue
+		}
+
+		object := testStruct{}
+		if err := c.Unmarshal(data, &object); err != nil {
+			t.Errorf("%s should not error %v", name, err)
+		}
+
+		if object.Name != "name" || object.Count != 2 {
+			t.Errorf("%s unexpected object %+v", name, object)
+		}
+	}
+}
+
+func Test_Binary_follows_redis_rules(t *testing.T) {
+	data, _ := Binary.Marshal(true)
+	if string(data) != "1" {
+		t.Errorf("expected 1, got %s", data)
+	}
+
+	if _, err := Binary.Marshal(testStruct{}); err == nil {
+		t.Error("should error on struct without BinaryMarshaler")
+	}
+
+	object := testStruct{}
+	if err := Binary.Unmarshal([]byte("value"), &object); err == nil {
+		t.Error("should error on struct without BinaryUnmarshaler")
+	}
+}
+
+func Test_Transform_roundtrip_and_reads_plain_values(t *testing.T) {
+	keys := map[string][]byte{"old": bytes.Repeat([]byte("o"), 32), "new": bytes.Repeat([]byte("n"), 16)}
+	for _, compression := range []Compression{Gzip, Snappy, Zstd} {
+		c, err := Transform(JSON, &TransformOption{
+			Compression:          compression,
+			CompressionThreshold: 10,
+			EncryptionKeys:       keys,
+			EncryptionKeyID:      "new",
+		})
+		if err != nil {
+			t.Fatal("should not error ", err)
+		}
+
+		data, err := c.Marshal(testStruct{Name: strings.Repeat("name", 10), Count: 2})
+		if err != nil {
+			t.Fatal("should not error ", err)
+		}
+
+		object := testStruct{}
+		if err := c.Unmarshal(data, &object); err != nil || object.Count != 2 {
+			t.Errorf("%s unexpected object %+v %v", compression, object, err)
+		}
+
+		plain, _ := JSON.Marshal(testStruct{Name: "plain", Count: 3})
+		if err := c.Unmarshal(plain, &object); err != nil || object.Name != "plain" {
+			t.Errorf("%s should read values written before the transform %+v %v", compression, object, err)
+		}
+	}
+}
+
+func Test_Transform_decrypts_with_rotated_key(t *testing.T) {
+	keys := map[string][]byte{"old": bytes.Repeat([]byte("o"), 32)}
+	before, _ := Transform(Binary, &TransformOption{EncryptionKeys: keys, EncryptionKeyID: "old"})
+	data, _ := before.Marshal("secret")
+
+	keys = map[string][]byte{"old": keys["old"], "new": bytes.Repeat([]byte("n"), 32)}
+	after, _ := Transform(Binary, &TransformOption{EncryptionKeys: keys, EncryptionKeyID: "new"})
+
+	if bytes.Contains(data, []byte("secret")) {
+		t.Error("value should be encrypted")
+	}
+
+	val, err := after.(*transformCodec).transformers[0].Decode(data)
+	if err != nil || string(val) != "secret" {
+		t.Errorf("unexpected decrypted value %q %v", val, err)
+	}
+}
@@ -0,0 +1,288 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+const (
+	Gzip   Compression = "gzip"
+	Snappy Compression = "snappy"
+	Zstd   Compression = "zstd"
+
+	DefaultCompressionThreshold = 1024
+
+	compressed = 'Z'
+	encrypted  = 'E'
+)
+
+// magic starts every transformed value, the values written without a
+// transformer do not carry it and are read unchanged.
+var magic = []byte{0xC0, 0xDE}
+
+type (
+	Compression string
+
+	// Transformer rewrites the bytes produced by a Codec before they are stored.
+	// Decode must return the data unchanged when it was not encoded by the transformer.
+	Transformer interface {
+		Encode([]byte) ([]byte, error)
+		Decode([]byte) ([]byte, error)
+	}
+
+	TransformOption struct {
+		// Compression compresses the values larger than CompressionThreshold bytes.
+		Compression          Compression
+		CompressionThreshold int
+		// EncryptionKeys enables AES-GCM encryption, values are encrypted with the
+		// key of EncryptionKeyID and decrypted with the key recorded in their header
+		// so old keys can be kept around while rotating.
+		EncryptionKeys  map[string][]byte
+		EncryptionKeyID string
+	}
+
+	transformCodec struct {
+		codec        Codec
+		transformers []Transformer
+	}
+
+	compressor struct {
+		algorithm Compression
+		threshold int
+		encoder   *zstd.Encoder
+		decoder   *zstd.Decoder
+	}
+
+	encryptor struct {
+		keyID string
+		aeads map[string]cipher.AEAD
+	}
+)
+
+// Transform returns c with the compression and encryption of option applied,
+// c is returned as is when option enables neither.
+func Transform(c Codec, option *TransformOption) (Codec, error) {
+	transformers := make([]Transformer, 0, 2)
+
+	if option.Compression != "" {
+		t, err := NewCompressor(option.Compression, option.CompressionThreshold)
+		if err != nil {
+			return nil, err
+		}
+		transformers = append(transformers, t)
+	}
+
+	if len(option.EncryptionKeys) > 0 {
+		t, err := NewEncryptor(option.EncryptionKeys, option.EncryptionKeyID)
+		if err != nil {
+			return nil, err
+		}
+		transformers = append(transformers, t)
+	}
+
+	if len(transformers) == 0 {
+		return c, nil
+	}
+
+	return &transformCodec{codec: c, transformers: transformers}, nil
+}
+
+func (t *transformCodec) Marshal(value interface{}) ([]byte, error) {
+	data, err := t.codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, transformer := range t.transformers {
+		if data, err = transformer.Encode(data); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+func (t *transformCodec) Unmarshal(data []byte, object interface{}) error {
+	var err error
+	for i := len(t.transformers) - 1; i >= 0; i-- {
+		if data, err = t.transformers[i].Decode(data); err != nil {
+			return err
+		}
+	}
+
+	return t.codec.Unmarshal(data, object)
+}
+
+// NewCompressor compresses the values larger than threshold bytes, smaller
+// values are stored as is.
+func NewCompressor(algorithm Compression, threshold int) (Transformer, error) {
+	if threshold == 0 {
+		threshold = DefaultCompressionThreshold
+	}
+
+	c := &compressor{algorithm: algorithm, threshold: threshold}
+
+	switch algorithm {
+	case Gzip, Snappy:
+	case Zstd:
+		var err error
+		if c.encoder, err = zstd.NewWriter(nil); err != nil {
+			return nil, errors.Wrap(err, "failed to create zstd encoder")
+		}
+		if c.decoder, err = zstd.NewReader(nil); err != nil {
+			return nil, errors.Wrap(err, "failed to create zstd decoder")
+		}
+	default:
+		return nil, errors.Errorf("invalid compression %s", algorithm)
+	}
+
+	return c, nil
+}
+
+func (c *compressor) Encode(data []byte) ([]byte, error) {
+	if len(data) < c.threshold {
+		return data, nil
+	}
+
+	header := append(append([]byte{}, magic...), compressed, c.algorithm[0])
+
+	switch c.algorithm {
+	case Gzip:
+		buf := bytes.NewBuffer(header)
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, errors.Wrap(err, "failed to compress value")
+		}
+		if err := w.Close(); err != nil {
+			return nil, errors.Wrap(err, "failed to compress value")
+		}
+		return buf.Bytes(), nil
+	case Snappy:
+		return append(header, snappy.Encode(nil, data)...), nil
+	default:
+		return c.encoder.EncodeAll(data, header), nil
+	}
+}
+
+// Decode reads every supported algorithm so the compression can be changed
+// without losing the values already stored.
+func (c *compressor) Decode(data []byte) ([]byte, error) {
+	if !hasHeader(data, compressed) || len(data) < len(magic)+2 {
+		return data, nil
+	}
+
+	body := data[len(magic)+2:]
+	switch data[len(magic)+1] {
+	case Gzip[0]:
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decompress value")
+		}
+		defer r.Close()
+
+		val, err := ioutil.ReadAll(r)
+		return val, errors.Wrap(err, "failed to decompress value")
+	case Snappy[0]:
+		val, err := snappy.Decode(nil, body)
+		return val, errors.Wrap(err, "failed to decompress value")
+	case Zstd[0]:
+		decoder := c.decoder
+		if decoder == nil {
+			var err error
+			if decoder, err = zstd.NewReader(nil); err != nil {
+				return nil, errors.Wrap(err, "failed to create zstd decoder")
+			}
+			defer decoder.Close()
+		}
+
+		val, err := decoder.DecodeAll(body, nil)
+		return val, errors.Wrap(err, "failed to decompress value")
+	default:
+		return nil, errors.Errorf("unknown compression %q", data[len(magic)+1])
+	}
+}
+
+// NewEncryptor encrypts with AES-GCM using the key of keyID, keys must be 16,
+// 24 or 32 bytes long and their id at most 255 bytes.
+func NewEncryptor(keys map[string][]byte, keyID string) (Transformer, error) {
+	if _, ok := keys[keyID]; !ok {
+		return nil, errors.Errorf("encryption key %s is not registered", keyID)
+	}
+
+	e := &encryptor{keyID: keyID, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if len(id) > 255 {
+			return nil, errors.Errorf("encryption key id %s is too long", id)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid encryption key %s", id)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid encryption key %s", id)
+		}
+		e.aeads[id] = aead
+	}
+
+	return e, nil
+}
+
+func (e *encryptor) Encode(data []byte) ([]byte, error) {
+	aead := e.aeads[e.keyID]
+
+	header := append(append([]byte{}, magic...), encrypted, byte(len(e.keyID)))
+	header = append(header, e.keyID...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	// - the header is authenticated so the key id can not be swapped
+	return aead.Seal(append(header, nonce...), nonce, data, header), nil
+}
+
+func (e *encryptor) Decode(data []byte) ([]byte, error) {
+	if !hasHeader(data, encrypted) || len(data) < len(magic)+2 {
+		return data, nil
+	}
+
+	end := len(magic) + 2 + int(data[len(magic)+1])
+	if len(data) < end {
+		return nil, errors.New("invalid encrypted value")
+	}
+
+	keyID := string(data[len(magic)+2 : end])
+	aead, ok := e.aeads[keyID]
+	if !ok {
+		return nil, errors.Errorf("encryption key %s is not registered", keyID)
+	}
+
+	if len(data) < end+aead.NonceSize() {
+		return nil, errors.New("invalid encrypted value")
+	}
+
+	nonce := data[end : end+aead.NonceSize()]
+	val, err := aead.Open(nil, nonce, data[end+aead.NonceSize():], data[:end])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt value")
+	}
+
+	return val, nil
+}
+
+func hasHeader(data []byte, kind byte) bool {
+	return len(data) > len(magic) && bytes.HasPrefix(data, magic) && data[len(magic)] == kind
+}
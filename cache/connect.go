@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+// TLSConfig builds the TLS configuration of a redis connection. The server is
+// verified with caFile, or the system roots when it is empty, and certFile and
+// keyFile authenticate the client when both are set.
+func TLSConfig(caFile, certFile, keyFile, serverName string, insecureSkipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	if caFile != "" {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read CA file %s", caFile)
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("no certificate found in CA file %s", caFile)
+		}
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load client certificate %s", certFile)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// Authenticate returns an OnConnect hook logging in as an ACL user, go-redis
+// only sends the password form of AUTH. The database is selected once the
+// connection is authenticated, so the client must be created without password
+// and database.
+func Authenticate(username, password string, db int) func(*redis.Conn) error {
+	return func(conn *redis.Conn) error {
+		auth := redis.NewStatusCmd("auth", username, password)
+		if err := conn.Process(auth); err != nil {
+			return errors.Wrapf(err, "failed to authenticate as %s", username)
+		}
+
+		if db > 0 {
+			if err := conn.Select(db).Err(); err != nil {
+				return errors.Wrapf(err, "failed to select database %d", db)
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,175 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/bloom"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultPrefix      = "idempotency:"
+	DefaultTTL         = 24 * time.Hour
+	DefaultInFlightTTL = time.Minute
+)
+
+var (
+	ErrInProgress = errors.New("request is already in progress")
+	ErrKeyReused  = errors.New("idempotency key was used for another request")
+)
+
+type (
+	Option struct {
+		Prefix string
+		// TTL is how long completed responses are replayed.
+		TTL time.Duration
+		// InFlightTTL bounds how long a request holds its key, it should be longer
+		// than the handlers since a duplicate can run once it elapsed.
+		InFlightTTL time.Duration
+		// Filter skips the response lookup of the keys that were never seen.
+		Filter bloom.Filter
+	}
+
+	// Response is the outcome of a completed request.
+	Response struct {
+		Fingerprint string              `json:"fingerprint"`
+		Status      int                 `json:"status"`
+		Header      map[string][]string `json:"header"`
+		Body        []byte              `json:"body"`
+	}
+
+	Store interface {
+		// BeginWithContext claims key for the request identified by fingerprint. It
+		// returns the response of a completed request, ErrInProgress while another
+		// request holds key and ErrKeyReused when key completed another request.
+		BeginWithContext(ctx context.Context, key, fingerprint string) (*Response, error)
+		Begin(key, fingerprint string) (*Response, error)
+		CompleteWithContext(ctx context.Context, key string, response *Response) error
+		Complete(key string, response *Response) error
+		// AbortWithContext releases key so the request can be retried.
+		AbortWithContext(ctx context.Context, key string) error
+		Abort(key string) error
+	}
+
+	store struct {
+		option  *Option
+		cache   cache.Cache
+		counter cache.Counter
+	}
+
+	// response breaks the recursion of the codecs honouring encoding.BinaryMarshaler.
+	response Response
+)
+
+func getOption(option *Option) {
+	if option.Prefix == "" {
+		option.Prefix = DefaultPrefix
+	}
+
+	if option.TTL == 0 {
+		option.TTL = DefaultTTL
+	}
+
+	if option.InFlightTTL == 0 {
+		option.InFlightTTL = DefaultInFlightTTL
+	}
+}
+
+// New creates a Store on a cache supporting counters, such as the redis and memory caches.
+func New(client cache.Cache, option *Option) (Store, error) {
+	counter, ok := client.(cache.Counter)
+	if !ok {
+		return nil, errors.New("cache client does not support counters")
+	}
+
+	getOption(option)
+	return &store{option: option, cache: client, counter: counter}, nil
+}
+
+func (s *store) BeginWithContext(ctx context.Context, key, fingerprint string) (*Response, error) {
+	if s.seen(ctx, key) {
+		res := &Response{}
+		err := s.cache.GetWithContext(ctx, s.responseKey(key), res)
+		if err == nil {
+			if res.Fingerprint != fingerprint {
+				return nil, ErrKeyReused
+			}
+			return res, nil
+		}
+
+		if errors.Cause(err) != redis.Nil {
+			return nil, errors.Wrapf(err, "failed to get idempotent response of key %s!", key)
+		}
+	}
+
+	claims, err := s.counter.IncrWithContext(ctx, s.claimKey(key), s.option.InFlightTTL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to claim idempotency key %s!", key)
+	}
+
+	if claims > 1 {
+		return nil, ErrInProgress
+	}
+
+	return nil, nil
+}
+
+func (s *store) Begin(key, fingerprint string) (*Response, error) {
+	return s.BeginWithContext(context.Background(), key, fingerprint)
+}
+
+func (s *store) CompleteWithContext(ctx context.Context, key string, response *Response) error {
+	if err := s.cache.SetWithExpirationWithContext(ctx, s.responseKey(key), response, s.option.TTL); err != nil {
+		return errors.Wrapf(err, "failed to store idempotent response of key %s!", key)
+	}
+
+	return nil
+}
+
+func (s *store) Complete(key string, response *Response) error {
+	return s.CompleteWithContext(context.Background(), key, response)
+}
+
+func (s *store) AbortWithContext(ctx context.Context, key string) error {
+	if err := s.cache.RemoveWithContext(ctx, s.claimKey(key)); err != nil {
+		return errors.Wrapf(err, "failed to release idempotency key %s!", key)
+	}
+
+	return nil
+}
+
+func (s *store) Abort(key string) error {
+	return s.AbortWithContext(context.Background(), key)
+}
+
+// seen tells whether key may have been used before, keys the filter never saw
+// are new and their response does not need to be looked up.
+func (s *store) seen(ctx context.Context, key string) bool {
+	if s.option.Filter == nil {
+		return true
+	}
+
+	added, err := s.option.Filter.AddWithContext(ctx, key)
+	return err != nil || !added
+}
+
+func (s *store) responseKey(key string) string {
+	return s.option.Prefix + "response:" + key
+}
+
+func (s *store) claimKey(key string) string {
+	return s.option.Prefix + "claim:" + key
+}
+
+func (r *Response) MarshalBinary() ([]byte, error) {
+	return json.Marshal((*response)(r))
+}
+
+func (r *Response) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, (*response)(r))
+}
@@ -0,0 +1,163 @@
+package idempotency
+
+import (
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jajotz/utilities-golang/cache/bloom"
+	"github.com/jajotz/utilities-golang/cache/memory"
+	"github.com/jajotz/utilities-golang/cache/redis"
+	"github.com/jajotz/utilities-golang/cache/redistest"
+	"github.com/jajotz/utilities-golang/http"
+)
+
+func Test_Begin_replays_completed_response(t *testing.T) {
+	s, err := New(memory.New(), &Option{})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if res, err := s.Begin("order-1", "fingerprint"); res != nil || err != nil {
+		t.Fatalf("expected a new request, got %+v %v", res, err)
+	}
+
+	if _, err := s.Begin("order-1", "fingerprint"); err != ErrInProgress {
+		t.Errorf("expected ErrInProgress, got %v", err)
+	}
+
+	if err := s.Complete("order-1", &Response{Fingerprint: "fingerprint", Status: 201, Body: []byte(`{"id":1}`)}); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	res, err := s.Begin("order-1", "fingerprint")
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if res == nil || res.Status != 201 || string(res.Body) != `{"id":1}` {
+		t.Errorf("unexpected response %+v", res)
+	}
+
+	if _, err := s.Begin("order-1", "other"); err != ErrKeyReused {
+		t.Errorf("expected ErrKeyReused, got %v", err)
+	}
+}
+
+func Test_Abort_releases_key(t *testing.T) {
+	s, _ := New(memory.New(), &Option{})
+
+	_, _ = s.Begin("order-1", "fingerprint")
+	if err := s.Abort("order-1"); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if res, err := s.Begin("order-1", "fingerprint"); res != nil || err != nil {
+		t.Errorf("expected the request to be retried, got %+v %v", res, err)
+	}
+}
+
+func Test_Begin_with_filter(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	client, err := redis.New(&redis.Option{Address: server.Addr()})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	filter, err := bloom.New(client, &bloom.Option{Key: "idempotency:filter", Capacity: 100})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	s, err := New(client, &Option{Filter: filter})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	// - a response of a key the filter never saw is not looked up
+	_ = client.Set("idempotency:response:order-1", &Response{Fingerprint: "fingerprint", Status: 201})
+	if res, err := s.Begin("order-1", "fingerprint"); res != nil || err != nil {
+		t.Fatalf("expected a new request, got %+v %v", res, err)
+	}
+
+	if err := s.Complete("order-1", &Response{Fingerprint: "fingerprint", Status: 200, Body: []byte("ok")}); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	res, err := s.Begin("order-1", "fingerprint")
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if res == nil || res.Status != 200 || string(res.Body) != "ok" {
+		t.Errorf("expected the completed response to be replayed, got %+v", res)
+	}
+
+	if _, err := s.Begin("order-1", "other"); err != ErrKeyReused {
+		t.Errorf("expected ErrKeyReused, got %v", err)
+	}
+}
+
+type testContext struct {
+	http.RequestContext
+	request  *nethttp.Request
+	response *httptest.ResponseRecorder
+}
+
+func (c *testContext) Request() *nethttp.Request {
+	return c.request
+}
+
+func (c *testContext) Response() nethttp.ResponseWriter {
+	return c.response
+}
+
+func newTestContext(body string) *testContext {
+	return &testContext{
+		request:  httptest.NewRequest(nethttp.MethodPost, "/orders", strings.NewReader(body)),
+		response: httptest.NewRecorder(),
+	}
+}
+
+func Test_Fingerprint_keeps_body_readable(t *testing.T) {
+	c := newTestContext(`{"id":1}`)
+
+	fingerprint, err := Fingerprint(c, DefaultMaxBodySize)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if other, _ := Fingerprint(newTestContext(`{"id":2}`), DefaultMaxBodySize); other == fingerprint {
+		t.Error("requests with different bodies should have different fingerprints")
+	}
+
+	body, _ := ioutil.ReadAll(c.request.Body)
+	if string(body) != `{"id":1}` {
+		t.Errorf("unexpected body %s", body)
+	}
+}
+
+func Test_Fingerprint_includes_query(t *testing.T) {
+	first, second := newTestContext(`{"id":1}`), newTestContext(`{"id":1}`)
+	first.request.URL.RawQuery = "dry_run=true"
+
+	a, _ := Fingerprint(first, DefaultMaxBodySize)
+	b, _ := Fingerprint(second, DefaultMaxBodySize)
+	if a == b {
+		t.Error("requests with different queries should have different fingerprints")
+	}
+}
+
+func Test_Fingerprint_rejects_large_body(t *testing.T) {
+	if _, err := Fingerprint(newTestContext(strings.Repeat("a", 11)), 10); err != ErrBodyTooLarge {
+		t.Errorf("expected ErrBodyTooLarge, got %v", err)
+	}
+
+	if _, err := Fingerprint(newTestContext(strings.Repeat("a", 10)), 10); err != nil {
+		t.Error("should not error ", err)
+	}
+}
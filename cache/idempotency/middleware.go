@@ -0,0 +1,152 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	nethttp "net/http"
+	"time"
+
+	"github.com/jajotz/utilities-golang/http"
+	"github.com/jajotz/utilities-golang/shared/dto"
+	"github.com/jajotz/utilities-golang/util/mwerror"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultHeader = "Idempotency-Key"
+	// ReplayedHeader is set on the responses replayed from the store.
+	ReplayedHeader = "Idempotent-Replayed"
+	// DefaultMaxBodySize bounds the request body read to fingerprint a request.
+	DefaultMaxBodySize = 1 << 20
+)
+
+var ErrBodyTooLarge = errors.New("request body is too large")
+
+type KeyFunc func(http.RequestContext) string
+
+// ByHeader reads the idempotency key from the header name, requests without it are not tracked.
+func ByHeader(name string) KeyFunc {
+	return func(c http.RequestContext) string {
+		return c.Request().Header.Get(name)
+	}
+}
+
+// Middleware runs the requests of a key once and replays their response to the
+// duplicates, it is registered with http.Server Wrap. Failed requests, either
+// returning an error or a 5xx status, release their key to be retried.
+// Requests are let through when the store itself fails. The requests of a key
+// with a body over maxBodySize are rejected, zero uses DefaultMaxBodySize.
+func Middleware(store Store, key KeyFunc, maxBodySize int64) http.MiddlewareFunc {
+	if maxBodySize == 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(c http.RequestContext) error {
+			k := key(c)
+			if k == "" {
+				return next(c)
+			}
+
+			fingerprint, err := Fingerprint(c, maxBodySize)
+			if err == ErrBodyTooLarge {
+				return reject(c, mwerror.BAD_REQUEST, err)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			ctx := c.Request().Context()
+			res, err := store.BeginWithContext(ctx, k, fingerprint)
+			switch err {
+			case nil:
+			case ErrInProgress:
+				return reject(c, mwerror.REQUEST_IN_PROGRESS, err)
+			case ErrKeyReused:
+				return reject(c, mwerror.IDEMPOTENCY_KEY_REUSED, err)
+			default:
+				c.Logger().Error(errors.Wrap(err, "failed to check idempotency key"))
+				return next(c)
+			}
+
+			if res != nil {
+				return replay(c, res)
+			}
+
+			recorder := http.Record(c)
+			if err := next(c); err != nil || recorder == nil || recorder.Status >= 500 {
+				if e := store.AbortWithContext(ctx, k); e != nil {
+					c.Logger().Error(e)
+				}
+				return err
+			}
+
+			if err := store.CompleteWithContext(ctx, k, &Response{
+				Fingerprint: fingerprint,
+				Status:      recorder.Status,
+				Header:      c.Response().Header(),
+				Body:        recorder.Body.Bytes(),
+			}); err != nil {
+				c.Logger().Error(err)
+			}
+
+			return nil
+		}
+	}
+}
+
+// Fingerprint hashes the method, path, query and body of the request to tell apart
+// the requests reusing a key, the body is left readable for the handlers. It
+// returns ErrBodyTooLarge when the body is over maxBodySize bytes.
+func Fingerprint(c http.RequestContext, maxBodySize int64) (string, error) {
+	req := c.Request()
+
+	body, err := ioutil.ReadAll(nethttp.MaxBytesReader(c.Response(), req.Body, maxBodySize))
+	if err != nil {
+		// - the reader fails once the limit is read
+		if int64(len(body)) == maxBodySize {
+			return "", ErrBodyTooLarge
+		}
+		return "", errors.Wrap(err, "failed to read request body")
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.Path + "?" + req.URL.RawQuery + "\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func replay(c http.RequestContext, res *Response) error {
+	header := c.Response().Header()
+	for name, values := range res.Header {
+		header[name] = values
+	}
+	header.Set(ReplayedHeader, "true")
+
+	c.Response().WriteHeader(res.Status)
+	if _, err := c.Response().Write(res.Body); err != nil {
+		return errors.Wrap(err, "failed to replay idempotent response")
+	}
+
+	return nil
+}
+
+func reject(c http.RequestContext, code string, err error) error {
+	mwErr := mwerror.New(code, err)
+	if err := c.JSON(mwErr.GetHTTPStatus(), &dto.BaseResponseDto{
+		Code:       mwErr.GetCode(),
+		Message:    mwErr.GetMessage(),
+		Errors:     mwErr.GetErrors(),
+		ServerTime: time.Now().Unix(),
+	}); err != nil {
+		return err
+	}
+
+	return mwErr
+}
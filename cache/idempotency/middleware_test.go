@@ -0,0 +1,129 @@
+package idempotency
+
+import (
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jajotz/utilities-golang/cache/memory"
+	"github.com/jajotz/utilities-golang/http"
+	"github.com/jajotz/utilities-golang/shared/dto"
+	"github.com/jajotz/utilities-golang/util/mwerror"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *http.Server {
+	store, err := New(memory.New(), &Option{})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	s := http.New()
+	s.Wrap(Middleware(store, ByHeader(DefaultHeader), 0))
+	s.POST("/orders", handler)
+	return s
+}
+
+func serve(s *http.Server, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(nethttp.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set(DefaultHeader, key)
+	res := httptest.NewRecorder()
+	s.ServeHTTP(res, req)
+	return res
+}
+
+func code(t *testing.T, res *httptest.ResponseRecorder) string {
+	body := dto.BaseResponseDto{}
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatal("should not error ", err)
+	}
+	return body.Code
+}
+
+func Test_Middleware_replays_response(t *testing.T) {
+	calls := 0
+	s := newTestServer(t, func(c http.RequestContext) error {
+		calls++
+		return c.String(nethttp.StatusCreated, "created")
+	})
+
+	first := serve(s, "order-1", `{"id":1}`)
+	if first.Code != nethttp.StatusCreated || first.Header().Get(ReplayedHeader) != "" {
+		t.Fatalf("unexpected first response %d %+v", first.Code, first.Header())
+	}
+
+	second := serve(s, "order-1", `{"id":1}`)
+	if second.Code != nethttp.StatusCreated || second.Body.String() != "created" {
+		t.Errorf("expected the response to be replayed, got %d %s", second.Code, second.Body.String())
+	}
+
+	if second.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("expected %s header, got %+v", ReplayedHeader, second.Header())
+	}
+
+	if calls != 1 {
+		t.Errorf("expected the handler to run once, got %d", calls)
+	}
+
+	// - requests without a key are not tracked
+	serve(s, "", `{"id":1}`)
+	if calls != 2 {
+		t.Errorf("expected the handler to run without a key, got %d", calls)
+	}
+}
+
+func Test_Middleware_rejects_request_in_progress(t *testing.T) {
+	var s *http.Server
+	var duplicate *httptest.ResponseRecorder
+	s = newTestServer(t, func(c http.RequestContext) error {
+		if duplicate == nil {
+			duplicate = serve(s, "order-1", `{"id":1}`)
+		}
+		return c.String(nethttp.StatusCreated, "created")
+	})
+
+	if res := serve(s, "order-1", `{"id":1}`); res.Code != nethttp.StatusCreated {
+		t.Fatalf("expected 201, got %d %s", res.Code, res.Body.String())
+	}
+
+	if duplicate.Code != nethttp.StatusConflict || code(t, duplicate) != mwerror.REQUEST_IN_PROGRESS {
+		t.Errorf("expected 409 %s, got %d %s", mwerror.REQUEST_IN_PROGRESS, duplicate.Code, duplicate.Body.String())
+	}
+}
+
+func Test_Middleware_rejects_reused_key(t *testing.T) {
+	s := newTestServer(t, func(c http.RequestContext) error {
+		return c.String(nethttp.StatusCreated, "created")
+	})
+
+	serve(s, "order-1", `{"id":1}`)
+	res := serve(s, "order-1", `{"id":2}`)
+	if res.Code != nethttp.StatusUnprocessableEntity || code(t, res) != mwerror.IDEMPOTENCY_KEY_REUSED {
+		t.Errorf("expected 422 %s, got %d %s", mwerror.IDEMPOTENCY_KEY_REUSED, res.Code, res.Body.String())
+	}
+}
+
+func Test_Middleware_aborts_failed_request(t *testing.T) {
+	calls := 0
+	s := newTestServer(t, func(c http.RequestContext) error {
+		calls++
+		if calls == 1 {
+			return c.String(nethttp.StatusInternalServerError, "failed")
+		}
+		return c.String(nethttp.StatusCreated, "created")
+	})
+
+	if res := serve(s, "order-1", `{"id":1}`); res.Code != nethttp.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", res.Code)
+	}
+
+	res := serve(s, "order-1", `{"id":1}`)
+	if res.Code != nethttp.StatusCreated || res.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("expected the failed request to be retried, got %d %+v", res.Code, res.Header())
+	}
+
+	if calls != 2 {
+		t.Errorf("expected the handler to run twice, got %d", calls)
+	}
+}
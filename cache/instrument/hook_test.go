@@ -0,0 +1,130 @@
+package instrument_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/redis"
+	rediscluster "github.com/jajotz/utilities-golang/cache/redis-cluster"
+	"github.com/jajotz/utilities-golang/cache/redistest"
+)
+
+type recorder struct {
+	mu        sync.Mutex
+	commands  []*cache.Command
+	pipelines [][]*cache.Command
+}
+
+func (r *recorder) BeforeProcess(ctx context.Context, cmd *cache.Command) context.Context {
+	return ctx
+}
+
+func (r *recorder) AfterProcess(ctx context.Context, cmd *cache.Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, cmd)
+}
+
+func (r *recorder) BeforeProcessPipeline(ctx context.Context, cmds []*cache.Command) context.Context {
+	return ctx
+}
+
+func (r *recorder) AfterProcessPipeline(ctx context.Context, cmds []*cache.Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pipelines = append(r.pipelines, cmds)
+}
+
+func (r *recorder) last() *cache.Command {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.commands[len(r.commands)-1]
+}
+
+func (r *recorder) names() map[string]bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make(map[string]bool)
+	for _, cmd := range r.commands {
+		names[cmd.Name] = true
+	}
+	for _, cmds := range r.pipelines {
+		for _, cmd := range cmds {
+			names[cmd.Name] = true
+		}
+	}
+	return names
+}
+
+func Test_Hooks_report_commands(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	hook := &recorder{}
+	c, err := redis.New(&redis.Option{Address: server.Addr(), Hooks: []cache.Hook{hook}})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+	defer c.Close()
+
+	_ = c.Set("user:1", "value")
+	if cmd := hook.last(); cmd.Name != "set" || cmd.Key != "user:1" || cmd.Read || cmd.Err != nil || cmd.Duration <= 0 {
+		t.Errorf("unexpected set %+v", cmd)
+	}
+
+	_ = c.HGet("user:1", "field", new(string))
+	if cmd := hook.last(); cmd.Name != "hget" || cmd.Err == nil {
+		t.Errorf("expected failed hget, got %+v", cmd)
+	}
+
+	var value []byte
+	_ = c.Get("user:2", &value)
+	if cmd := hook.last(); cmd.Name != "get" || cmd.Key != "user:2" || !cmd.Read || cmd.Hit || cmd.Err != nil {
+		t.Errorf("expected get miss, got %+v", cmd)
+	}
+
+	_ = c.GetWithContext(context.Background(), "user:1", &value)
+	if cmd := hook.last(); cmd.Name != "get" || !cmd.Read || !cmd.Hit || cmd.Err != nil {
+		t.Errorf("expected get hit, got %+v", cmd)
+	}
+
+	p := c.Pipeline()
+	_ = p.Set("user:3", "value")
+	p.Incr("counter")
+	if err := p.Exec(); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if len(hook.pipelines) != 1 {
+		t.Fatalf("expected 1 pipeline, got %d", len(hook.pipelines))
+	}
+
+	cmds := hook.pipelines[0]
+	if len(cmds) != 2 || cmds[0].Name != "set" || cmds[0].Key != "user:3" || cmds[1].Name != "incr" || cmds[1].Key != "counter" || cmds[1].Duration <= 0 {
+		t.Errorf("unexpected pipeline %+v %+v", cmds[0], cmds[1])
+	}
+}
+
+func Test_Hooks_report_node_commands_of_cluster(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	hook := &recorder{}
+	c, err := rediscluster.New(&rediscluster.Option{Address: []string{server.Addr()}, Hooks: []cache.Hook{hook}})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+	defer c.Close()
+
+	_ = c.Set("user:1", "value")
+	if _, err := c.(cache.Scanner).UnlinkByPattern("user:*", 10); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if names := hook.names(); !names["scan"] || !names["unlink"] {
+		t.Errorf("expected scan and unlink to be reported, got %+v", names)
+	}
+}
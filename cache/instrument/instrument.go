@@ -0,0 +1,146 @@
+package instrument
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+)
+
+type (
+	// Processor is implemented by the go-redis clients.
+	Processor interface {
+		WrapProcess(fn func(oldProcess func(cmd redis.Cmder) error) func(cmd redis.Cmder) error)
+		WrapProcessPipeline(fn func(oldProcess func([]redis.Cmder) error) func([]redis.Cmder) error)
+	}
+)
+
+var reads = map[string]bool{
+	"get":      true,
+	"getrange": true,
+	"mget":     true,
+	"exists":   true,
+	"hget":     true,
+	"hmget":    true,
+	"hgetall":  true,
+	"hexists":  true,
+	"zscore":   true,
+	"zrange":   true,
+	"smembers": true,
+}
+
+// Wrap reports the commands processed by r to hooks. The wrappers are bound to
+// ctx so r should be a client returned by WithContext and not the shared one.
+func Wrap(ctx context.Context, r Processor, hooks []cache.Hook) {
+	if len(hooks) == 0 {
+		return
+	}
+
+	r.WrapProcess(func(old func(redis.Cmder) error) func(redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			command := newCommand(cmd)
+
+			c := ctx
+			for _, hook := range hooks {
+				c = hook.BeforeProcess(c, command)
+			}
+
+			start := time.Now()
+			err := old(cmd)
+			finish(command, cmd, time.Since(start))
+
+			for _, hook := range hooks {
+				hook.AfterProcess(c, command)
+			}
+
+			return err
+		}
+	})
+
+	r.WrapProcessPipeline(func(old func([]redis.Cmder) error) func([]redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			commands := make([]*cache.Command, len(cmds))
+			for i, cmd := range cmds {
+				commands[i] = newCommand(cmd)
+			}
+
+			c := ctx
+			for _, hook := range hooks {
+				c = hook.BeforeProcessPipeline(c, commands)
+			}
+
+			start := time.Now()
+			err := old(cmds)
+			duration := time.Since(start)
+			for i, cmd := range cmds {
+				finish(commands[i], cmd, duration)
+			}
+
+			for _, hook := range hooks {
+				hook.AfterProcessPipeline(c, commands)
+			}
+
+			return err
+		}
+	})
+}
+
+func newCommand(cmd redis.Cmder) *cache.Command {
+	name := strings.ToLower(cmd.Name())
+	command := &cache.Command{Name: name, Read: reads[name]}
+
+	args := cmd.Args()
+	switch {
+	case (name == "eval" || name == "evalsha") && len(args) > 3:
+		command.Key, _ = args[3].(string)
+	case len(args) > 1:
+		command.Key, _ = args[1].(string)
+	}
+
+	return command
+}
+
+func finish(command *cache.Command, cmd redis.Cmder, duration time.Duration) {
+	command.Duration = duration
+
+	err := cmd.Err()
+	if err == redis.Nil {
+		return
+	}
+
+	if err != nil {
+		command.Err = err
+		return
+	}
+
+	command.Hit = command.Read && found(cmd)
+}
+
+// found tells whether a successful read returned a value, go-redis only
+// reports redis.Nil for single values.
+func found(cmd redis.Cmder) bool {
+	switch c := cmd.(type) {
+	case *redis.IntCmd:
+		return c.Val() > 0
+	case *redis.BoolCmd:
+		return c.Val()
+	case *redis.StringStringMapCmd:
+		return len(c.Val()) > 0
+	case *redis.StringSliceCmd:
+		return len(c.Val()) > 0
+	case *redis.ZSliceCmd:
+		return len(c.Val()) > 0
+	case *redis.SliceCmd:
+		for _, v := range c.Val() {
+			if v != nil {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
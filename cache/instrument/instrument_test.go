@@ -0,0 +1,51 @@
+package instrument
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+)
+
+func Test_finish_reports_hits_and_misses(t *testing.T) {
+	cases := []struct {
+		name string
+		cmd  redis.Cmder
+		hit  bool
+		err  bool
+	}{
+		{name: "get", cmd: redis.NewStringResult("value", nil), hit: true},
+		{name: "get", cmd: redis.NewStringResult("", redis.Nil)},
+		{name: "hgetall", cmd: redis.NewStringStringMapResult(map[string]string{}, nil)},
+		{name: "mget", cmd: redis.NewSliceResult([]interface{}{nil, "value"}, nil), hit: true},
+		{name: "exists", cmd: redis.NewIntResult(0, nil)},
+		{name: "get", cmd: redis.NewStringResult("", errors.New("connection refused")), err: true},
+		{name: "set", cmd: redis.NewStatusResult("OK", nil)},
+	}
+
+	for _, c := range cases {
+		command := &cache.Command{Name: c.name, Read: reads[c.name]}
+		finish(command, c.cmd, time.Millisecond)
+
+		if command.Hit != c.hit {
+			t.Errorf("%s %v: expected hit %v", c.name, c.cmd, c.hit)
+		}
+
+		if (command.Err != nil) != c.err {
+			t.Errorf("%s %v: unexpected error %v", c.name, c.cmd, command.Err)
+		}
+	}
+}
+
+func Test_newCommand_extracts_key(t *testing.T) {
+	if cmd := newCommand(redis.NewStringCmd("GET", "user:1")); cmd.Name != "get" || cmd.Key != "user:1" || !cmd.Read {
+		t.Errorf("unexpected command %+v", cmd)
+	}
+
+	if cmd := newCommand(redis.NewCmd("evalsha", "sha", 1, "counter")); cmd.Key != "counter" {
+		t.Errorf("unexpected command %+v", cmd)
+	}
+}
@@ -0,0 +1,72 @@
+package newrelic
+
+import (
+	"context"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	newrelic "github.com/newrelic/go-agent"
+)
+
+type (
+	// Option describes the datastore instance reported on the segments.
+	Option struct {
+		Host         string
+		PortPathOrID string
+		DatabaseName string
+	}
+
+	segmentKey struct{}
+
+	hook struct {
+		option *Option
+	}
+)
+
+// New reports the commands as datastore segments of the transaction found in
+// the context, which is set by the newrelic middleware on the request context.
+func New(option *Option) cache.Hook {
+	if option == nil {
+		option = &Option{}
+	}
+
+	return &hook{option: option}
+}
+
+func (h *hook) BeforeProcess(ctx context.Context, cmd *cache.Command) context.Context {
+	return h.start(ctx, cmd.Name)
+}
+
+func (h *hook) AfterProcess(ctx context.Context, cmd *cache.Command) {
+	h.end(ctx)
+}
+
+func (h *hook) BeforeProcessPipeline(ctx context.Context, cmds []*cache.Command) context.Context {
+	return h.start(ctx, "pipeline")
+}
+
+func (h *hook) AfterProcessPipeline(ctx context.Context, cmds []*cache.Command) {
+	h.end(ctx)
+}
+
+func (h *hook) start(ctx context.Context, operation string) context.Context {
+	txn := newrelic.FromContext(ctx)
+	if txn == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, segmentKey{}, &newrelic.DatastoreSegment{
+		StartTime:    newrelic.StartSegmentNow(txn),
+		Product:      newrelic.DatastoreRedis,
+		Operation:    operation,
+		Host:         h.option.Host,
+		PortPathOrID: h.option.PortPathOrID,
+		DatabaseName: h.option.DatabaseName,
+	})
+}
+
+func (h *hook) end(ctx context.Context) {
+	if segment, ok := ctx.Value(segmentKey{}).(*newrelic.DatastoreSegment); ok {
+		_ = segment.End()
+	}
+}
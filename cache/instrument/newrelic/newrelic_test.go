@@ -0,0 +1,51 @@
+package newrelic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	newrelic "github.com/newrelic/go-agent"
+)
+
+func Test_hook_ignores_context_without_transaction(t *testing.T) {
+	h := New(nil)
+
+	ctx := context.Background()
+	if c := h.BeforeProcess(ctx, &cache.Command{Name: "get"}); c != ctx {
+		t.Error("context without transaction should be kept")
+	}
+}
+
+func Test_hook_records_datastore_segments(t *testing.T) {
+	config := newrelic.NewConfig("cache", "")
+	config.Enabled = false
+	app, err := newrelic.NewApplication(config)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	txn := app.StartTransaction("request", nil, nil)
+	defer txn.End()
+
+	h := New(&Option{Host: "localhost", PortPathOrID: "6379"})
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	c := h.BeforeProcess(ctx, &cache.Command{Name: "get"})
+	segment, ok := c.Value(segmentKey{}).(*newrelic.DatastoreSegment)
+	if !ok {
+		t.Fatal("expected a datastore segment")
+	}
+
+	if segment.Product != newrelic.DatastoreRedis || segment.Operation != "get" || segment.Host != "localhost" || segment.PortPathOrID != "6379" {
+		t.Errorf("unexpected segment %+v", segment)
+	}
+	h.AfterProcess(c, &cache.Command{Name: "get"})
+
+	c = h.BeforeProcessPipeline(ctx, []*cache.Command{{Name: "set"}, {Name: "incr"}})
+	if segment, ok := c.Value(segmentKey{}).(*newrelic.DatastoreSegment); !ok || segment.Operation != "pipeline" {
+		t.Errorf("expected a pipeline segment, got %+v", segment)
+	}
+	h.AfterProcessPipeline(c, nil)
+}
@@ -0,0 +1,148 @@
+package prometheus
+
+import (
+	"context"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	DefaultNamespace = "redis"
+	DefaultSubsystem = "cache"
+)
+
+type (
+	Option struct {
+		Namespace string
+		Subsystem string
+		// ConstLabels tells apart the metrics of several cache clients, e.g. {"client": "session"}.
+		ConstLabels prometheus.Labels
+		Buckets     []float64
+		// Registerer defaults to prometheus.DefaultRegisterer.
+		Registerer prometheus.Registerer
+	}
+
+	hook struct {
+		duration *prometheus.HistogramVec
+		lookups  *prometheus.CounterVec
+	}
+)
+
+func getOption(option *Option) {
+	if option.Namespace == "" {
+		option.Namespace = DefaultNamespace
+	}
+
+	if option.Subsystem == "" {
+		option.Subsystem = DefaultSubsystem
+	}
+
+	if len(option.Buckets) == 0 {
+		option.Buckets = prometheus.DefBuckets
+	}
+
+	if option.Registerer == nil {
+		option.Registerer = prometheus.DefaultRegisterer
+	}
+}
+
+// New registers the command duration histogram, labelled by command and
+// status, and the lookups counter, labelled by command and hit or miss result.
+func New(option *Option) (cache.Hook, error) {
+	getOption(option)
+
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   option.Namespace,
+		Subsystem:   option.Subsystem,
+		Name:        "command_duration_seconds",
+		Help:        "Duration of the redis commands.",
+		ConstLabels: option.ConstLabels,
+		Buckets:     option.Buckets,
+	}, []string{"command", "status"})
+
+	lookups := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   option.Namespace,
+		Subsystem:   option.Subsystem,
+		Name:        "lookups_total",
+		Help:        "Number of redis lookups by result.",
+		ConstLabels: option.ConstLabels,
+	}, []string{"command", "result"})
+
+	h := &hook{duration: duration, lookups: lookups}
+	if err := register(option.Registerer, duration, &h.duration); err != nil {
+		return nil, err
+	}
+
+	if err := register(option.Registerer, lookups, &h.lookups); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// register reuses the collector already registered by another hook with the same option.
+func register(registerer prometheus.Registerer, collector prometheus.Collector, target interface{}) error {
+	err := registerer.Register(collector)
+	if err == nil {
+		return nil
+	}
+
+	registered, ok := err.(prometheus.AlreadyRegisteredError)
+	if !ok {
+		return errors.Wrap(err, "failed to register redis metrics")
+	}
+
+	switch t := target.(type) {
+	case **prometheus.HistogramVec:
+		if existing, ok := registered.ExistingCollector.(*prometheus.HistogramVec); ok {
+			*t = existing
+			return nil
+		}
+	case **prometheus.CounterVec:
+		if existing, ok := registered.ExistingCollector.(*prometheus.CounterVec); ok {
+			*t = existing
+			return nil
+		}
+	}
+
+	return errors.Wrap(err, "failed to register redis metrics")
+}
+
+func (h *hook) BeforeProcess(ctx context.Context, cmd *cache.Command) context.Context {
+	return ctx
+}
+
+func (h *hook) AfterProcess(ctx context.Context, cmd *cache.Command) {
+	h.observe(cmd)
+}
+
+func (h *hook) BeforeProcessPipeline(ctx context.Context, cmds []*cache.Command) context.Context {
+	return ctx
+}
+
+func (h *hook) AfterProcessPipeline(ctx context.Context, cmds []*cache.Command) {
+	for _, cmd := range cmds {
+		h.observe(cmd)
+	}
+}
+
+func (h *hook) observe(cmd *cache.Command) {
+	status := "ok"
+	if cmd.Err != nil {
+		status = "error"
+	}
+	h.duration.WithLabelValues(cmd.Name, status).Observe(cmd.Duration.Seconds())
+
+	if !cmd.Read || cmd.Err != nil {
+		return
+	}
+
+	result := "miss"
+	if cmd.Hit {
+		result = "hit"
+	}
+	h.lookups.WithLabelValues(cmd.Name, result).Inc()
+}
@@ -0,0 +1,66 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_hook_observes_durations_and_lookups(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	h, err := New(&Option{Registerer: registry})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	ctx := context.Background()
+	h.AfterProcess(ctx, &cache.Command{Name: "get", Read: true, Hit: true, Duration: time.Millisecond})
+	h.AfterProcess(ctx, &cache.Command{Name: "get", Read: true, Duration: time.Millisecond})
+	h.AfterProcessPipeline(ctx, []*cache.Command{
+		{Name: "set", Duration: time.Millisecond},
+		{Name: "get", Read: true, Err: errors.New("connection refused"), Duration: time.Millisecond},
+	})
+
+	metrics := h.(*hook)
+	if count := testutil.CollectAndCount(metrics.duration); count != 3 {
+		t.Errorf("expected get/ok, get/error and set/ok durations, got %d series", count)
+	}
+
+	if count := testutil.CollectAndCount(metrics.lookups); count != 2 {
+		t.Errorf("failed lookups should not be counted, got %d series", count)
+	}
+
+	if hits := testutil.ToFloat64(metrics.lookups.WithLabelValues("get", "hit")); hits != 1 {
+		t.Errorf("expected 1 hit, got %v", hits)
+	}
+
+	if misses := testutil.ToFloat64(metrics.lookups.WithLabelValues("get", "miss")); misses != 1 {
+		t.Errorf("expected 1 miss, got %v", misses)
+	}
+}
+
+func Test_New_reuses_registered_metrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	first, err := New(&Option{Registerer: registry})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	second, err := New(&Option{Registerer: registry})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	first.AfterProcess(context.Background(), &cache.Command{Name: "get", Read: true})
+	second.AfterProcess(context.Background(), &cache.Command{Name: "get", Read: true})
+
+	if misses := testutil.ToFloat64(first.(*hook).lookups.WithLabelValues("get", "miss")); misses != 2 {
+		t.Errorf("expected both hooks to share the counter, got %v", misses)
+	}
+}
@@ -0,0 +1,54 @@
+package instrument
+
+import (
+	"context"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/logs"
+)
+
+const DefaultSlowThreshold = 100 * time.Millisecond
+
+type (
+	slowLog struct {
+		log       logs.Logger
+		threshold time.Duration
+	}
+)
+
+// SlowLog warns about the commands and pipelines slower than threshold,
+// which defaults to DefaultSlowThreshold.
+func SlowLog(log logs.Logger, threshold time.Duration) cache.Hook {
+	if log == nil {
+		log, _ = logs.DefaultLog()
+	}
+
+	if threshold <= 0 {
+		threshold = DefaultSlowThreshold
+	}
+
+	return &slowLog{log: log, threshold: threshold}
+}
+
+func (s *slowLog) BeforeProcess(ctx context.Context, cmd *cache.Command) context.Context {
+	return ctx
+}
+
+func (s *slowLog) AfterProcess(ctx context.Context, cmd *cache.Command) {
+	if cmd.Duration >= s.threshold {
+		s.log.Warningf("slow redis command %s %s took %s", cmd.Name, cmd.Key, cmd.Duration)
+	}
+}
+
+func (s *slowLog) BeforeProcessPipeline(ctx context.Context, cmds []*cache.Command) context.Context {
+	return ctx
+}
+
+func (s *slowLog) AfterProcessPipeline(ctx context.Context, cmds []*cache.Command) {
+	if len(cmds) == 0 || cmds[0].Duration < s.threshold {
+		return
+	}
+
+	s.log.Warningf("slow redis pipeline of %d commands starting with %s %s took %s", len(cmds), cmds[0].Name, cmds[0].Key, cmds[0].Duration)
+}
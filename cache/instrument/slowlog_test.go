@@ -0,0 +1,57 @@
+package instrument
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/logs"
+)
+
+type testLogger struct {
+	logs.Logger
+	warnings []string
+}
+
+func (l *testLogger) Warningf(format string, args ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+func Test_SlowLog_warns_about_slow_commands(t *testing.T) {
+	log := &testLogger{}
+	hook := SlowLog(log, 10*time.Millisecond)
+
+	hook.AfterProcess(context.Background(), &cache.Command{Name: "get", Key: "fast", Duration: time.Millisecond})
+	hook.AfterProcess(context.Background(), &cache.Command{Name: "get", Key: "slow", Duration: 20 * time.Millisecond})
+	hook.AfterProcessPipeline(context.Background(), []*cache.Command{{Name: "set", Key: "fast", Duration: time.Millisecond}})
+	hook.AfterProcessPipeline(context.Background(), []*cache.Command{
+		{Name: "set", Key: "slow", Duration: 30 * time.Millisecond},
+		{Name: "incr", Key: "counter", Duration: 30 * time.Millisecond},
+	})
+
+	if len(log.warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %+v", log.warnings)
+	}
+
+	if log.warnings[0] != "slow redis command get slow took 20ms" {
+		t.Errorf("unexpected warning %s", log.warnings[0])
+	}
+
+	if log.warnings[1] != "slow redis pipeline of 2 commands starting with set slow took 30ms" {
+		t.Errorf("unexpected warning %s", log.warnings[1])
+	}
+}
+
+func Test_SlowLog_defaults_threshold(t *testing.T) {
+	log := &testLogger{}
+	hook := SlowLog(log, 0)
+
+	hook.AfterProcess(context.Background(), &cache.Command{Name: "get", Duration: DefaultSlowThreshold - time.Millisecond})
+	hook.AfterProcess(context.Background(), &cache.Command{Name: "get", Duration: DefaultSlowThreshold})
+
+	if len(log.warnings) != 1 {
+		t.Errorf("expected 1 warning, got %+v", log.warnings)
+	}
+}
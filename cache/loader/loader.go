@@ -0,0 +1,235 @@
+package loader
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/logs"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	NegativeSuffix = ":not-found"
+
+	minSweep = 1024
+)
+
+// ErrNotFound is returned by a LoadFunc when the value does not exist in the
+// source of truth, it is cached for NegativeTTL when negative caching is enabled.
+var ErrNotFound = errors.New("not found")
+
+type (
+	LoadFunc func() (interface{}, error)
+
+	Option struct {
+		// Beta tunes the probabilistic early refresh, values above 1 favour earlier
+		// refreshes and zero disables it.
+		Beta float64
+		// NegativeTTL enables caching of ErrNotFound results.
+		NegativeTTL time.Duration
+		Log         logs.Logger
+	}
+
+	Loader interface {
+		// GetOrLoad reads key into data, on a miss it calls loader once per key
+		// across concurrent callers and stores the result for ttl.
+		GetOrLoad(key string, ttl time.Duration, data interface{}, loader LoadFunc) error
+	}
+
+	metadata struct {
+		delta    time.Duration
+		expireAt time.Time
+	}
+
+	readThrough struct {
+		option *Option
+		client cache.Cache
+		group  singleflight.Group
+		mu     sync.Mutex
+		meta   map[string]metadata
+		// sweepAt is the size of meta that triggers the removal of its expired
+		// entries, so the keys that are not read again don't pile up.
+		sweepAt int
+		// random draws the early refresh gaps, in [0, 1).
+		random func() float64
+	}
+)
+
+func getOption(option *Option) error {
+	if option.Beta < 0 {
+		return errors.New("invalid early refresh beta")
+	}
+
+	if option.Log == nil {
+		logger, _ := logs.DefaultLog()
+		option.Log = logger
+	}
+
+	return nil
+}
+
+func New(client cache.Cache, option *Option) (Loader, error) {
+	if err := getOption(option); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &readThrough{
+		option:  option,
+		client:  client,
+		meta:    make(map[string]metadata),
+		sweepAt: minSweep,
+		random:  rand.Float64,
+	}, nil
+}
+
+func (l *readThrough) GetOrLoad(key string, ttl time.Duration, data interface{}, loader LoadFunc) error {
+	err := l.client.Get(key, data)
+	if err == nil {
+		if l.shouldRefresh(key) {
+			go l.group.Do(key, func() (interface{}, error) {
+				return l.load(key, ttl, loader)
+			})
+		}
+		return nil
+	}
+
+	if errors.Cause(err) != redis.Nil {
+		l.option.Log.Errorf("failed to read cache with key %s, loading from source: %s", key, err.Error())
+	} else if l.option.NegativeTTL > 0 && l.isNegative(key) {
+		return ErrNotFound
+	}
+
+	value, err, _ := l.group.Do(key, func() (interface{}, error) {
+		return l.load(key, ttl, loader)
+	})
+	if err != nil {
+		return err
+	}
+
+	if assign(value, data) {
+		return nil
+	}
+
+	return l.client.Get(key, data)
+}
+
+func (l *readThrough) load(key string, ttl time.Duration, loader LoadFunc) (interface{}, error) {
+	start := time.Now()
+	value, err := loader()
+	if errors.Cause(err) == ErrNotFound {
+		if l.option.NegativeTTL > 0 {
+			if err := l.client.SetWithExpiration(key+NegativeSuffix, true, l.option.NegativeTTL); err != nil {
+				l.option.Log.Errorf("failed to cache not found result of key %s: %s", key, err.Error())
+			}
+		}
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load key %s", key)
+	}
+
+	if err := l.client.SetWithExpiration(key, value, ttl); err != nil {
+		l.option.Log.Errorf("failed to cache loaded key %s: %s", key, err.Error())
+		return value, nil
+	}
+
+	if l.option.Beta > 0 && ttl > 0 {
+		l.mu.Lock()
+		l.meta[key] = metadata{delta: time.Since(start), expireAt: time.Now().Add(ttl)}
+		l.sweep()
+		l.mu.Unlock()
+	}
+
+	return value, nil
+}
+
+// shouldRefresh implements the XFetch algorithm: the closer the key is to its
+// expiry and the longer it took to load, the likelier it is refreshed early.
+func (l *readThrough) shouldRefresh(key string) bool {
+	if l.option.Beta <= 0 {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	meta, ok := l.meta[key]
+	if !ok {
+		return false
+	}
+
+	now := time.Now()
+	if now.After(meta.expireAt) {
+		delete(l.meta, key)
+		return false
+	}
+
+	gap := time.Duration(float64(meta.delta) * l.option.Beta * -math.Log(l.random()))
+	if now.Add(gap).Before(meta.expireAt) {
+		return false
+	}
+
+	// - only the first caller past the threshold triggers the refresh
+	delete(l.meta, key)
+	return true
+}
+
+// sweep removes the expired metadata once meta doubled since the last sweep,
+// it is called with mu held.
+func (l *readThrough) sweep() {
+	if len(l.meta) < l.sweepAt {
+		return
+	}
+
+	now := time.Now()
+	for key, meta := range l.meta {
+		if now.After(meta.expireAt) {
+			delete(l.meta, key)
+		}
+	}
+
+	l.sweepAt = 2 * len(l.meta)
+	if l.sweepAt < minSweep {
+		l.sweepAt = minSweep
+	}
+}
+
+func (l *readThrough) isNegative(key string) bool {
+	val, err := l.client.MGet([]string{key + NegativeSuffix})
+	if err != nil {
+		return false
+	}
+
+	return len(val) > 0 && val[0] != nil
+}
+
+// assign copies value into the pointer data when their types are compatible.
+func assign(value interface{}, data interface{}) bool {
+	dst := reflect.ValueOf(data)
+	if value == nil || dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return false
+	}
+
+	src := reflect.ValueOf(value)
+	target := dst.Elem()
+
+	if src.Type().AssignableTo(target.Type()) {
+		target.Set(src)
+		return true
+	}
+
+	if src.Kind() == reflect.Ptr && !src.IsNil() && src.Elem().Type().AssignableTo(target.Type()) {
+		target.Set(src.Elem())
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,147 @@
+package loader
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache/memory"
+)
+
+type testValue struct {
+	value string
+}
+
+func (v *testValue) MarshalBinary() ([]byte, error) {
+	return []byte(v.value), nil
+}
+
+func (v *testValue) UnmarshalBinary(data []byte) error {
+	v.value = string(data)
+	return nil
+}
+
+func Test_GetOrLoad_loads_once_for_concurrent_callers(t *testing.T) {
+	l, _ := New(memory.New(), &Option{})
+
+	var calls int32
+	loader := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return &testValue{value: "value"}, nil
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			object := testValue{}
+			if err := l.GetOrLoad("key", time.Minute, &object, loader); err != nil {
+				t.Error("should not error ", err)
+			}
+			if object.value != "value" {
+				t.Errorf("expected value, got %s", object.value)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("expected a single load, got %d", calls)
+	}
+}
+
+func Test_GetOrLoad_caches_not_found(t *testing.T) {
+	l, _ := New(memory.New(), &Option{NegativeTTL: time.Minute})
+
+	var calls int32
+	loader := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, ErrNotFound
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := l.GetOrLoad("key", time.Minute, &testValue{}, loader); err != ErrNotFound {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("expected a single load, got %d", calls)
+	}
+}
+
+func Test_GetOrLoad_refreshes_before_expiry(t *testing.T) {
+	client := memory.New()
+	l, _ := New(client, &Option{Beta: 100})
+	rt := l.(*readThrough)
+
+	var calls int32
+	loader := func() (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return &testValue{value: "v" + strconv.Itoa(int(n))}, nil
+	}
+
+	if err := l.GetOrLoad("key", time.Minute, &testValue{}, loader); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	// - a draw close to 1 makes a gap far shorter than the ttl
+	rt.random = func() float64 { return 0.999 }
+	object := testValue{}
+	if err := l.GetOrLoad("key", time.Minute, &object, loader); err != nil || object.value != "v1" {
+		t.Fatalf("expected cached v1, got %s %v", object.value, err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected no early refresh, got %d loads", n)
+	}
+
+	// - a draw close to 0 makes a gap beyond the ttl, 10ms * 100 * 690
+	rt.random = func() float64 { return 1e-300 }
+	object = testValue{}
+	if err := l.GetOrLoad("key", time.Minute, &object, loader); err != nil || object.value != "v1" {
+		t.Fatalf("expected cached v1 while refreshing, got %s %v", object.value, err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		object = testValue{}
+		if err := client.Get("key", &object); err == nil && object.value == "v2" {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected key to be refreshed in the background, got %s", object.value)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("expected a single early refresh, got %d loads", n)
+	}
+}
+
+func Test_sweep_removes_expired_metadata(t *testing.T) {
+	l := &readThrough{meta: make(map[string]metadata), sweepAt: minSweep}
+	expired := time.Now().Add(-time.Second)
+	for i := 0; i < minSweep; i++ {
+		l.meta[strconv.Itoa(i)] = metadata{expireAt: expired}
+	}
+	l.meta["live"] = metadata{expireAt: time.Now().Add(time.Minute)}
+
+	l.sweep()
+
+	if len(l.meta) != 1 {
+		t.Errorf("expected only the live key to be kept, got %d keys", len(l.meta))
+	}
+
+	if l.sweepAt != minSweep {
+		t.Errorf("expected next sweep at %d, got %d", minSweep, l.sweepAt)
+	}
+}
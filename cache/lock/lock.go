@@ -0,0 +1,232 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultPrefix       = "lock:"
+	DefaultRetryBackoff = 100 * time.Millisecond
+)
+
+var (
+	// ErrNotObtained is returned when the lock is held by someone else.
+	ErrNotObtained = errors.New("lock not obtained")
+	// ErrNotHeld is returned when the lock expired or was taken over by another owner.
+	ErrNotHeld = errors.New("lock not held")
+	// ErrInvalidTTL is returned for a ttl under a millisecond, redis would leave
+	// the lock without expiration or delete it.
+	ErrInvalidTTL = errors.New("lock ttl must be at least a millisecond")
+
+	release = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`)
+
+	extend = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	ttl = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pttl", KEYS[1])
+end
+return -3`)
+)
+
+type (
+	Option struct {
+		// Prefix is prepended to every lock key.
+		Prefix string
+		// RetryBackoff is the delay between two attempts of a blocking acquire.
+		RetryBackoff time.Duration
+		// MaxRetry bounds the attempts of a blocking acquire, zero retries until the context is done.
+		MaxRetry int
+	}
+
+	Locker interface {
+		// TryAcquire makes a single attempt and returns ErrNotObtained when the lock is taken,
+		// ttl has to be positive.
+		TryAcquire(key string, ttl time.Duration) (Lock, error)
+
+		// AcquireWithContext retries until the lock is obtained, MaxRetry is reached or ctx is done.
+		AcquireWithContext(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+		Acquire(key string, ttl time.Duration) (Lock, error)
+	}
+
+	Lock interface {
+		Key() string
+		Token() string
+		TTL() (time.Duration, error)
+		Extend(ttl time.Duration) error
+		Release() error
+	}
+
+	locker struct {
+		option *Option
+		client redis.UniversalClient
+	}
+
+	lock struct {
+		client redis.UniversalClient
+		key    string
+		token  string
+	}
+)
+
+func getOption(option *Option) {
+	if option.Prefix == "" {
+		option.Prefix = DefaultPrefix
+	}
+
+	if option.RetryBackoff == 0 {
+		option.RetryBackoff = DefaultRetryBackoff
+	}
+}
+
+// New creates a Locker on top of one of the redis backed caches,
+// cache/redis, cache/redis-cluster or cache/redis-universal.
+func New(client cache.Cache, option *Option) (Locker, error) {
+	instance, ok := client.(cache.Instance)
+	if !ok {
+		return nil, errors.New("cache client is not backed by redis")
+	}
+
+	getOption(option)
+
+	return &locker{option: option, client: instance.Instance()}, nil
+}
+
+func (l *locker) TryAcquire(key string, ttl time.Duration) (Lock, error) {
+	if ttl < time.Millisecond {
+		return nil, ErrInvalidTTL
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key = l.option.Prefix + key
+	ok, err := l.client.SetNX(key, token, ttl).Result()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to acquire lock %s", key)
+	}
+
+	if !ok {
+		return nil, ErrNotObtained
+	}
+
+	return &lock{client: l.client, key: key, token: token}, nil
+}
+
+func (l *locker) AcquireWithContext(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for attempt := 1; ; attempt++ {
+		lck, err := l.TryAcquire(key, ttl)
+		if err != ErrNotObtained {
+			return lck, err
+		}
+
+		if l.option.MaxRetry > 0 && attempt > l.option.MaxRetry {
+			return nil, ErrNotObtained
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(l.option.RetryBackoff)
+		} else {
+			timer.Reset(l.option.RetryBackoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "failed to acquire lock %s", key)
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *locker) Acquire(key string, ttl time.Duration) (Lock, error) {
+	return l.AcquireWithContext(context.Background(), key, ttl)
+}
+
+func (l *lock) Key() string {
+	return l.key
+}
+
+func (l *lock) Token() string {
+	return l.token
+}
+
+func (l *lock) TTL() (time.Duration, error) {
+	res, err := ttl.Run(l.client, []string{l.key}, l.token).Int64()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get ttl of lock %s", l.key)
+	}
+
+	if res == -3 {
+		return 0, ErrNotHeld
+	}
+
+	if res < 0 {
+		return 0, nil
+	}
+
+	return time.Duration(res) * time.Millisecond, nil
+}
+
+func (l *lock) Extend(duration time.Duration) error {
+	if duration < time.Millisecond {
+		return ErrInvalidTTL
+	}
+
+	res, err := extend.Run(l.client, []string{l.key}, l.token, int64(duration/time.Millisecond)).Int64()
+	if err != nil {
+		return errors.Wrapf(err, "failed to extend lock %s", l.key)
+	}
+
+	if res != 1 {
+		return ErrNotHeld
+	}
+
+	return nil
+}
+
+func (l *lock) Release() error {
+	res, err := release.Run(l.client, []string{l.key}, l.token).Int64()
+	if err != nil {
+		return errors.Wrapf(err, "failed to release lock %s", l.key)
+	}
+
+	if res != 1 {
+		return ErrNotHeld
+	}
+
+	return nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate lock token")
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,152 @@
+package lock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache/redis"
+	"github.com/jajotz/utilities-golang/cache/redistest"
+)
+
+func newLocker(t *testing.T) (Locker, *redistest.Server) {
+	server := redistest.Run(t)
+
+	client, err := redis.New(&redis.Option{Address: server.Addr()})
+	if err != nil {
+		server.Close()
+		t.Fatal("should not error ", err)
+	}
+
+	locker, err := New(client, &Option{RetryBackoff: time.Millisecond, MaxRetry: 2})
+	if err != nil {
+		server.Close()
+		t.Fatal("should not error ", err)
+	}
+
+	return locker, server
+}
+
+func Test_Acquire_and_Release(t *testing.T) {
+	locker, server := newLocker(t)
+	defer server.Close()
+
+	lck, err := locker.Acquire("job", time.Minute)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if ttl, err := lck.TTL(); err != nil || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("unexpected ttl %s %v", ttl, err)
+	}
+
+	if err := lck.Release(); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	if server.Exists("lock:job") {
+		t.Error("lock key should be removed on release")
+	}
+}
+
+func Test_Acquire_fails_while_held(t *testing.T) {
+	locker, server := newLocker(t)
+	defer server.Close()
+
+	if _, err := locker.TryAcquire("job", time.Minute); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if _, err := locker.TryAcquire("job", time.Minute); err != ErrNotObtained {
+		t.Errorf("expected ErrNotObtained, got %v", err)
+	}
+
+	if _, err := locker.Acquire("job", time.Minute); err != ErrNotObtained {
+		t.Errorf("expected ErrNotObtained after the retries, got %v", err)
+	}
+}
+
+func Test_Release_by_non_owner(t *testing.T) {
+	locker, server := newLocker(t)
+	defer server.Close()
+
+	lck, err := locker.TryAcquire("job", time.Second)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	server.FastForward(2 * time.Second)
+	if _, err := locker.TryAcquire("job", time.Minute); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if err := lck.Release(); err != ErrNotHeld {
+		t.Errorf("expected ErrNotHeld, got %v", err)
+	}
+
+	if !server.Exists("lock:job") {
+		t.Error("the lock of the new owner should be kept")
+	}
+}
+
+func Test_Extend(t *testing.T) {
+	locker, server := newLocker(t)
+	defer server.Close()
+
+	lck, err := locker.TryAcquire("job", time.Second)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if err := lck.Extend(time.Minute); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	server.FastForward(2 * time.Second)
+	if _, err := lck.TTL(); err != nil {
+		t.Error("extended lock should still be held ", err)
+	}
+
+	for _, duration := range []time.Duration{0, 500 * time.Microsecond} {
+		if err := lck.Extend(duration); err != ErrInvalidTTL {
+			t.Errorf("expected ErrInvalidTTL for %s, got %v", duration, err)
+		}
+	}
+
+	if _, err := lck.TTL(); err != nil {
+		t.Error("lock should still be held after an invalid extension ", err)
+	}
+}
+
+func Test_lock_expires(t *testing.T) {
+	locker, server := newLocker(t)
+	defer server.Close()
+
+	lck, err := locker.TryAcquire("job", time.Second)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	server.FastForward(2 * time.Second)
+	if _, err := lck.TTL(); err != ErrNotHeld {
+		t.Errorf("expected ErrNotHeld, got %v", err)
+	}
+
+	if err := lck.Extend(time.Minute); err != ErrNotHeld {
+		t.Errorf("expected ErrNotHeld, got %v", err)
+	}
+
+	if _, err := locker.TryAcquire("job", time.Minute); err != nil {
+		t.Error("should not error ", err)
+	}
+}
+
+func Test_TryAcquire_rejects_non_positive_ttl(t *testing.T) {
+	locker, server := newLocker(t)
+	defer server.Close()
+
+	for _, ttl := range []time.Duration{0, -time.Second, 500 * time.Microsecond} {
+		if _, err := locker.TryAcquire("job", ttl); err != ErrInvalidTTL {
+			t.Errorf("expected ErrInvalidTTL for %s, got %v", ttl, err)
+		}
+	}
+}
@@ -0,0 +1,515 @@
+package memory
+
+import (
+	"encoding"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/codec"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+type (
+	kind int
+
+	item struct {
+		kind     kind
+		value    string
+		hash     map[string]string
+		zset     []redis.Z
+		expireAt time.Time
+	}
+
+	memoryClient struct {
+		mu    sync.RWMutex
+		items map[string]*item
+
+		subMu       sync.RWMutex
+		subscribers map[*subscription]struct{}
+	}
+)
+
+const (
+	kindString kind = iota
+	kindHash
+	kindZSet
+	kindHyperLogLog
+)
+
+var errWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// New returns an in-process cache.Cache that mimics the behaviour of the redis
+// backed implementations, it is meant for unit tests and local development.
+func New() cache.Cache {
+	return &memoryClient{
+		items:       make(map[string]*item),
+		subscribers: make(map[*subscription]struct{}),
+	}
+}
+
+func (c *memoryClient) Ping() error {
+	return nil
+}
+
+func (c *memoryClient) SetWithExpiration(key string, value interface{}, duration time.Duration) error {
+	val, err := marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set cache with key %s!", key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it := &item{kind: kindString, value: val}
+	if duration > 0 {
+		it.expireAt = time.Now().Add(duration)
+	}
+	c.items[key] = it
+
+	return nil
+}
+
+func (c *memoryClient) Set(key string, value interface{}) error {
+	return c.SetWithExpiration(key, value, 0)
+}
+
+func (c *memoryClient) Get(key string, data interface{}) error {
+	if _, ok := data.(encoding.BinaryUnmarshaler); !ok {
+		return errors.New(fmt.Sprintf("failed to get cache with key %s!: redis: can't unmarshal (implement encoding.BinaryUnmarshaler)", key))
+	}
+
+	c.mu.Lock()
+	it, err := c.lookup(key, kindString)
+	c.mu.Unlock()
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if it == nil {
+		return errors.Wrapf(redis.Nil, "key %s does not exits", key)
+	}
+
+	if err := data.(encoding.BinaryUnmarshaler).UnmarshalBinary([]byte(it.value)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *memoryClient) Keys(pattern string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.keys(pattern), nil
+}
+
+func (c *memoryClient) Remove(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, key)
+	return nil
+}
+
+func (c *memoryClient) RemoveByPattern(pattern string, countPerLoop int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range c.keys(pattern) {
+		delete(c.items, key)
+	}
+
+	return nil
+}
+
+func (c *memoryClient) ScanKeys(pattern string, count int64) ([]string, error) {
+	return c.Keys(pattern)
+}
+
+func (c *memoryClient) UnlinkByPattern(pattern string, count int64) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := c.keys(pattern)
+	for _, key := range keys {
+		delete(c.items, key)
+	}
+
+	return int64(len(keys)), nil
+}
+
+func (c *memoryClient) FlushDatabase() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*item)
+	return nil
+}
+
+func (c *memoryClient) FlushAll() error {
+	return c.FlushDatabase()
+}
+
+func (c *memoryClient) Close() error {
+	return nil
+}
+
+func (c *memoryClient) SetZSetWithExpiration(key string, duration time.Duration, data ...redis.Z) error {
+	if err := c.SetZSet(key, data...); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(key, duration)
+	return nil
+}
+
+func (c *memoryClient) SetZSet(key string, data ...redis.Z) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(data) == 0 {
+		return errors.Wrapf(errors.New("ERR wrong number of arguments for 'zadd' command"), "failed to zadd cache with key %s!", key)
+	}
+
+	delete(c.items, key)
+
+	if _, err := c.zadd(key, data...); err != nil {
+		return errors.Wrapf(err, "failed to zadd cache with key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *memoryClient) GetZSet(key string) ([]redis.Z, error) {
+	c.mu.Lock()
+	it, err := c.lookup(key, kindZSet)
+	c.mu.Unlock()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run zrange command")
+	}
+
+	if it == nil || len(it.zset) <= 0 {
+		return nil, errors.New(fmt.Sprintf("key %s does not exits", key))
+	}
+
+	data := make([]redis.Z, len(it.zset))
+	copy(data, it.zset)
+	return data, nil
+}
+
+func (c *memoryClient) HMSetWithExpiration(key string, value map[string]interface{}, ttl time.Duration) error {
+	if err := c.HMSet(key, value); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(key, ttl)
+	return nil
+}
+
+func (c *memoryClient) HMSet(key string, value map[string]interface{}) error {
+	if len(value) == 0 {
+		return errors.Wrapf(errors.New("ERR wrong number of arguments for 'hmset' command"), "failed to HMSet cache with key %s!", key)
+	}
+
+	fields := make(map[string]string, len(value))
+	for field, v := range value {
+		val, err := marshal(v)
+		if err != nil {
+			return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
+		}
+		fields[field] = val
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.hmset(key, fields); err != nil {
+		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *memoryClient) HSetWithExpiration(key, field string, value interface{}, ttl time.Duration) error {
+	if err := c.HSet(key, field, value); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(key, ttl)
+	return nil
+}
+
+func (c *memoryClient) HSet(key, field string, value interface{}) error {
+	if err := c.HMSet(key, map[string]interface{}{field: value}); err != nil {
+		return errors.Wrapf(errors.Cause(err), "failed to HSet cache with key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *memoryClient) HMGet(key string, fields ...string) ([]interface{}, error) {
+	c.mu.Lock()
+	it, err := c.lookup(key, kindHash)
+	c.mu.Unlock()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	val := make([]interface{}, len(fields))
+	if it == nil {
+		return val, nil
+	}
+
+	for i, field := range fields {
+		if v, ok := it.hash[field]; ok {
+			val[i] = v
+		}
+	}
+
+	return val, nil
+}
+
+func (c *memoryClient) HGetAll(key string) (map[string]string, error) {
+	c.mu.Lock()
+	it, err := c.lookup(key, kindHash)
+	c.mu.Unlock()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	val := make(map[string]string)
+	if it == nil {
+		return val, nil
+	}
+
+	for field, v := range it.hash {
+		val[field] = v
+	}
+
+	return val, nil
+}
+
+func (c *memoryClient) HGet(key, field string, response interface{}) error {
+	if _, ok := response.(encoding.BinaryUnmarshaler); !ok {
+		return errors.New(fmt.Sprintf("failed to get cache with key %s!: redis: can't unmarshal (implement encoding.BinaryUnmarshaler)", key))
+	}
+
+	c.mu.Lock()
+	it, err := c.lookup(key, kindHash)
+	c.mu.Unlock()
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if it == nil {
+		return errors.Wrapf(redis.Nil, "key %s does not exits", key)
+	}
+
+	val, ok := it.hash[field]
+	if !ok {
+		return errors.Wrapf(redis.Nil, "key %s does not exits", key)
+	}
+
+	if err := response.(encoding.BinaryUnmarshaler).UnmarshalBinary([]byte(val)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *memoryClient) MGet(key []string) ([]interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	val := make([]interface{}, len(key))
+	for i, k := range key {
+		if it, err := c.lookup(k, kindString); err == nil && it != nil {
+			val[i] = it.value
+		}
+	}
+
+	return val, nil
+}
+
+func (c *memoryClient) Client() cache.Cache {
+	return c
+}
+
+func (c *memoryClient) Pipeline() cache.Pipe {
+	return &pipe{client: c}
+}
+
+// TxPipeline returns the same pipe as Pipeline, queued commands are always
+// applied under a single lock.
+func (c *memoryClient) TxPipeline() cache.Pipe {
+	return c.Pipeline()
+}
+
+// lookup returns the live item stored under key, evicting it when expired.
+// Caller must hold the write lock.
+func (c *memoryClient) lookup(key string, k kind) (*item, error) {
+	it, ok := c.items[key]
+	if !ok {
+		return nil, nil
+	}
+
+	if !it.expireAt.IsZero() && !time.Now().Before(it.expireAt) {
+		delete(c.items, key)
+		return nil, nil
+	}
+
+	if it.kind != k {
+		return nil, errWrongType
+	}
+
+	return it, nil
+}
+
+// hmset stores the marshaled fields. Caller must hold the write lock.
+func (c *memoryClient) hmset(key string, fields map[string]string) error {
+	it, err := c.lookup(key, kindHash)
+	if err != nil {
+		return err
+	}
+
+	if it == nil {
+		it = &item{kind: kindHash, hash: make(map[string]string)}
+		c.items[key] = it
+	}
+
+	for field, val := range fields {
+		it.hash[field] = val
+	}
+
+	return nil
+}
+
+// zadd adds or updates the members and returns how many were added.
+// Caller must hold the write lock.
+func (c *memoryClient) zadd(key string, data ...redis.Z) (int64, error) {
+	if len(data) == 0 {
+		return 0, errors.New("ERR wrong number of arguments for 'zadd' command")
+	}
+
+	it, err := c.lookup(key, kindZSet)
+	if err != nil {
+		return 0, err
+	}
+
+	members := make(map[string]redis.Z)
+	if it != nil {
+		for _, z := range it.zset {
+			members[z.Member.(string)] = z
+		}
+	}
+
+	var added int64
+	for _, z := range data {
+		member, err := marshal(z.Member)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := members[member]; !ok {
+			added++
+		}
+		members[member] = redis.Z{Score: z.Score, Member: member}
+	}
+
+	zset := make([]redis.Z, 0, len(members))
+	for _, z := range members {
+		zset = append(zset, z)
+	}
+	sortZSet(zset)
+
+	if it == nil {
+		it = &item{kind: kindZSet}
+		c.items[key] = it
+	}
+	it.zset = zset
+
+	return added, nil
+}
+
+// sortZSet orders members by score then lexicographically like redis.
+func sortZSet(zset []redis.Z) {
+	sort.Slice(zset, func(i, j int) bool {
+		if zset[i].Score != zset[j].Score {
+			return zset[i].Score < zset[j].Score
+		}
+		return zset[i].Member.(string) < zset[j].Member.(string)
+	})
+}
+
+// expire follows the EXPIRE semantic where a non positive ttl deletes the key.
+// Caller must hold the write lock.
+func (c *memoryClient) expire(key string, ttl time.Duration) {
+	it, ok := c.items[key]
+	if !ok {
+		return
+	}
+
+	if ttl <= 0 {
+		delete(c.items, key)
+		return
+	}
+
+	it.expireAt = time.Now().Add(ttl)
+}
+
+// keys returns the sorted live keys matching pattern. Caller must hold the write lock.
+func (c *memoryClient) keys(pattern string) []string {
+	now := time.Now()
+	keys := make([]string, 0)
+	for key, it := range c.items {
+		if !it.expireAt.IsZero() && !now.Before(it.expireAt) {
+			delete(c.items, key)
+			continue
+		}
+
+		if cache.Match(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *memoryClient) Decode(value interface{}, object interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return codec.Binary.Unmarshal([]byte(v), object)
+	case nil:
+		return redis.Nil
+	default:
+		return errors.New(fmt.Sprintf("can't decode value of type %T", value))
+	}
+}
+
+func marshal(value interface{}) (string, error) {
+	b, err := codec.Binary.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
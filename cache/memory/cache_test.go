@@ -0,0 +1,294 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/cachetest"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+type testValue struct {
+	value string
+}
+
+func (v *testValue) MarshalBinary() ([]byte, error) {
+	return []byte(v.value), nil
+}
+
+func (v *testValue) UnmarshalBinary(data []byte) error {
+	v.value = string(data)
+	return nil
+}
+
+func Test_conformance(t *testing.T) {
+	cachetest.Run(t, &cachetest.Option{New: func() (cache.Cache, error) { return New(), nil }})
+}
+
+func Test_Get_returns_value(t *testing.T) {
+	c := New()
+	if err := c.Set("key", &testValue{value: "value"}); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	object := testValue{}
+	if err := c.Get("key", &object); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	if object.value != "value" {
+		t.Errorf("expected value, got %s", object.value)
+	}
+}
+
+func Test_Get_returns_redis_nil_when_missing(t *testing.T) {
+	c := New()
+
+	err := c.Get("missing", &testValue{})
+	if errors.Cause(err) != redis.Nil {
+		t.Errorf("expected redis.Nil, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "does not exits") {
+		t.Errorf("unexpected error message %s", err.Error())
+	}
+}
+
+func Test_Get_requires_binary_unmarshaler(t *testing.T) {
+	c := New()
+	_ = c.Set("key", "value")
+
+	var object string
+	if err := c.Get("key", &object); err == nil {
+		t.Error("should error")
+	}
+}
+
+func Test_SetWithExpiration_expires(t *testing.T) {
+	c := New()
+	_ = c.SetWithExpiration("key", "value", 10*time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if err := c.Get("key", &testValue{}); errors.Cause(err) != redis.Nil {
+		t.Errorf("expected key to expire, got %v", err)
+	}
+}
+
+func Test_ZSet_is_sorted_by_score(t *testing.T) {
+	c := New()
+	_ = c.SetZSet("zset", redis.Z{Score: 2, Member: "b"}, redis.Z{Score: 1, Member: "a"})
+
+	data, err := c.GetZSet("zset")
+	if err != nil {
+		t.Error("should not error ", err)
+	}
+
+	if len(data) != 2 || data[0].Member != "a" || data[1].Member != "b" {
+		t.Errorf("unexpected zset %+v", data)
+	}
+}
+
+func Test_Hash_operations(t *testing.T) {
+	c := New()
+	_ = c.HMSet("hash", map[string]interface{}{"a": 1, "b": "two"})
+	_ = c.HSet("hash", "c", true)
+
+	all, err := c.HGetAll("hash")
+	if err != nil {
+		t.Error("should not error ", err)
+	}
+
+	if all["a"] != "1" || all["b"] != "two" || all["c"] != "1" {
+		t.Errorf("unexpected hash %+v", all)
+	}
+
+	values, _ := c.HMGet("hash", "a", "missing")
+	if values[0] != "1" || values[1] != nil {
+		t.Errorf("unexpected hmget result %+v", values)
+	}
+
+	if err := c.Get("hash", &testValue{}); err == nil {
+		t.Error("should error on wrong type")
+	}
+}
+
+func Test_RemoveByPattern_removes_matching_keys(t *testing.T) {
+	c := New()
+	_ = c.Set("user:1", "a")
+	_ = c.Set("user:2", "b")
+	_ = c.Set("order:1", "c")
+
+	if err := c.RemoveByPattern("user:*", 10); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	keys, _ := c.Keys("*")
+	if len(keys) != 1 || keys[0] != "order:1" {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+}
+
+func Test_Pipeline_applies_on_exec(t *testing.T) {
+	c := New()
+	p := c.Pipeline()
+	_ = p.Set("key", "value")
+
+	if err := c.Get("key", &testValue{}); errors.Cause(err) != redis.Nil {
+		t.Error("pipeline should not apply before exec")
+	}
+
+	if err := p.Exec(); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	values, _ := c.MGet([]string{"key", "missing"})
+	if values[0] != "value" || values[1] != nil {
+		t.Errorf("unexpected mget result %+v", values)
+	}
+}
+
+func Test_Pipeline_resolves_results_on_exec(t *testing.T) {
+	c := New()
+	_ = c.Set("key", "value")
+
+	p := c.TxPipeline()
+	get := p.Get("key")
+	missing := p.Get("missing")
+	incr := p.Incr("counter")
+	_ = p.HMSet("hash", map[string]interface{}{"a": 1, "b": "two"})
+	all := p.HGetAll("hash")
+	zadd := p.ZAdd("zset", redis.Z{Score: 1, Member: "a"}, redis.Z{Score: 2, Member: "b"})
+	expire := p.Expire("missing", time.Minute)
+	del := p.Del("key", "missing")
+
+	if err := p.Exec(); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	value := &testValue{}
+	if err := get.Scan(value); err != nil || value.value != "value" {
+		t.Errorf("unexpected get result %+v %v", value, err)
+	}
+
+	if err := missing.Scan(&testValue{}); errors.Cause(err) != redis.Nil {
+		t.Error("missing key should resolve to redis.Nil ", err)
+	}
+
+	if incr.Val() != 1 || zadd.Val() != 2 || expire.Val() || del.Val() != 1 {
+		t.Errorf("unexpected results incr=%d zadd=%d expire=%v del=%d", incr.Val(), zadd.Val(), expire.Val(), del.Val())
+	}
+
+	if hash := all.Val(); hash["a"] != "1" || hash["b"] != "two" {
+		t.Errorf("unexpected hgetall result %+v", hash)
+	}
+}
+
+func Test_PSubscribe_receives_matching_channels(t *testing.T) {
+	c := New().(cache.PubSub)
+	subscription, err := c.PSubscribe("config.*")
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	_ = c.Publish("orders", "ignored")
+	_ = c.Publish("config.reload", "now")
+
+	select {
+	case msg := <-subscription.Messages():
+		if msg.Channel != "config.reload" || msg.Pattern != "config.*" || msg.Payload != "now" {
+			t.Errorf("unexpected message %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected a message")
+	}
+
+	_ = subscription.Close()
+	if _, ok := <-subscription.Messages(); ok {
+		t.Error("messages should be closed")
+	}
+}
+
+func Test_UnlinkByPattern_reports_removed_keys(t *testing.T) {
+	c := New()
+	_ = c.Set("order:1", "a")
+	_ = c.Set("order:2", "b")
+	_ = c.Set("user:1", "c")
+
+	removed, err := c.(cache.Scanner).UnlinkByPattern("order:*", 1)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if removed != 2 {
+		t.Errorf("expected 2 removed keys, got %d", removed)
+	}
+}
+
+func Test_Counter_applies_ttl_on_first_write(t *testing.T) {
+	c := New().(*memoryClient)
+
+	if val, err := c.Incr("views", time.Minute); err != nil || val != 1 {
+		t.Fatalf("unexpected incr result %d %v", val, err)
+	}
+
+	first := c.items["views"].expireAt
+	if val, _ := c.IncrBy("views", 4, time.Hour); val != 5 {
+		t.Errorf("expected 5, got %d", val)
+	}
+
+	if !c.items["views"].expireAt.Equal(first) {
+		t.Error("ttl should only be applied when the key is created")
+	}
+}
+
+func Test_SetBit_follows_redis_bit_order(t *testing.T) {
+	c := New().(*memoryClient)
+
+	_, _ = c.SetBit("active", 0, 1, 0)
+	previous, _ := c.SetBit("active", 9, 1, 0)
+	count, _ := c.BitCount("active")
+
+	if previous != 0 || count != 2 || c.items["active"].value != "\x80\x40" {
+		t.Errorf("unexpected bitmap %q count %d", c.items["active"].value, count)
+	}
+}
+
+func Test_SortedSet_leaderboard(t *testing.T) {
+	c := New().(cache.SortedSet)
+	_, _ = c.ZAdd("board", redis.Z{Score: 10, Member: "alice"}, redis.Z{Score: 30, Member: "bob"}, redis.Z{Score: 20, Member: "carol"})
+
+	if score, _ := c.ZIncrBy("board", 25, "alice"); score != 35 {
+		t.Errorf("expected 35, got %f", score)
+	}
+
+	top, _ := c.ZRevRange("board", 0, 1)
+	if len(top) != 2 || top[0].Member != "alice" || top[1].Member != "bob" {
+		t.Errorf("unexpected top %+v", top)
+	}
+
+	page, _ := c.ZRangeByScore("board", redis.ZRangeBy{Min: "(20", Max: "+inf", Offset: 1, Count: 1})
+	if len(page) != 1 || page[0].Member != "alice" {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	if rank, _ := c.ZRevRank("board", "carol"); rank != 2 {
+		t.Errorf("expected rank 2, got %d", rank)
+	}
+
+	if _, err := c.ZScore("board", "dave"); errors.Cause(err) != redis.Nil {
+		t.Error("missing member should be redis.Nil ", err)
+	}
+
+	if removed, _ := c.ZRemRangeByRank("board", 0, 0); removed != 1 {
+		t.Errorf("expected 1 removed member, got %d", removed)
+	}
+
+	if card, _ := c.ZCard("board"); card != 2 {
+		t.Errorf("expected 2 members, got %d", card)
+	}
+}
@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+// alive reports the context error, the in-process store never blocks so the
+// context is only checked before running a command.
+func alive(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+
+	return errors.WithStack(ctx.Err())
+}
+
+func (c *memoryClient) PingWithContext(ctx context.Context) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.Ping()
+}
+
+func (c *memoryClient) SetWithExpirationWithContext(ctx context.Context, key string, value interface{}, duration time.Duration) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.SetWithExpiration(key, value, duration)
+}
+
+func (c *memoryClient) SetWithContext(ctx context.Context, key string, value interface{}) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.Set(key, value)
+}
+
+func (c *memoryClient) GetWithContext(ctx context.Context, key string, data interface{}) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.Get(key, data)
+}
+
+func (c *memoryClient) SetZSetWithExpirationWithContext(ctx context.Context, key string, duration time.Duration, data ...redis.Z) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.SetZSetWithExpiration(key, duration, data...)
+}
+
+func (c *memoryClient) SetZSetWithContext(ctx context.Context, key string, data ...redis.Z) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.SetZSet(key, data...)
+}
+
+func (c *memoryClient) GetZSetWithContext(ctx context.Context, key string) ([]redis.Z, error) {
+	if err := alive(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.GetZSet(key)
+}
+
+func (c *memoryClient) HMSetWithExpirationWithContext(ctx context.Context, key string, value map[string]interface{}, ttl time.Duration) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.HMSetWithExpiration(key, value, ttl)
+}
+
+func (c *memoryClient) HMSetWithContext(ctx context.Context, key string, value map[string]interface{}) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.HMSet(key, value)
+}
+
+func (c *memoryClient) HSetWithExpirationWithContext(ctx context.Context, key, field string, value interface{}, ttl time.Duration) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.HSetWithExpiration(key, field, value, ttl)
+}
+
+func (c *memoryClient) HSetWithContext(ctx context.Context, key, field string, value interface{}) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.HSet(key, field, value)
+}
+
+func (c *memoryClient) HMGetWithContext(ctx context.Context, key string, fields ...string) ([]interface{}, error) {
+	if err := alive(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.HMGet(key, fields...)
+}
+
+func (c *memoryClient) HGetAllWithContext(ctx context.Context, key string) (map[string]string, error) {
+	if err := alive(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.HGetAll(key)
+}
+
+func (c *memoryClient) HGetWithContext(ctx context.Context, key, field string, response interface{}) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.HGet(key, field, response)
+}
+
+func (c *memoryClient) MGetWithContext(ctx context.Context, key []string) ([]interface{}, error) {
+	if err := alive(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.MGet(key)
+}
+
+func (c *memoryClient) KeysWithContext(ctx context.Context, pattern string) ([]string, error) {
+	if err := alive(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.Keys(pattern)
+}
+
+func (c *memoryClient) RemoveWithContext(ctx context.Context, key string) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.Remove(key)
+}
+
+func (c *memoryClient) RemoveByPatternWithContext(ctx context.Context, pattern string, countPerLoop int64) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.RemoveByPattern(pattern, countPerLoop)
+}
+
+func (c *memoryClient) ScanKeysWithContext(ctx context.Context, pattern string, count int64) ([]string, error) {
+	if err := alive(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.ScanKeys(pattern, count)
+}
+
+func (c *memoryClient) UnlinkByPatternWithContext(ctx context.Context, pattern string, count int64) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.UnlinkByPattern(pattern, count)
+}
+
+func (c *memoryClient) FlushDatabaseWithContext(ctx context.Context) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.FlushDatabase()
+}
+
+func (c *memoryClient) FlushAllWithContext(ctx context.Context) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.FlushAll()
+}
+
+func (c *memoryClient) PipelineWithContext(ctx context.Context) cache.Pipe {
+	return c.Pipeline()
+}
+
+func (c *memoryClient) TxPipelineWithContext(ctx context.Context) cache.Pipe {
+	return c.TxPipeline()
+}
@@ -0,0 +1,280 @@
+package memory
+
+import (
+	"context"
+	"math/bits"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var errBitOffset = errors.New("ERR bit offset is not an integer or out of range")
+
+func (c *memoryClient) IncrWithContext(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(ctx, key, 1, ttl)
+}
+
+func (c *memoryClient) Incr(key string, ttl time.Duration) (int64, error) {
+	return c.IncrBy(key, 1, ttl)
+}
+
+func (c *memoryClient) DecrWithContext(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(ctx, key, -1, ttl)
+}
+
+func (c *memoryClient) Decr(key string, ttl time.Duration) (int64, error) {
+	return c.IncrBy(key, -1, ttl)
+}
+
+func (c *memoryClient) IncrByWithContext(ctx context.Context, key string, value int64, ttl time.Duration) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.IncrBy(key, value, ttl)
+}
+
+func (c *memoryClient) IncrBy(key string, value int64, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, err := c.lookup(key, kindString)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to incr cache with key %s!", key)
+	}
+
+	if it == nil {
+		it = c.create(key, &item{kind: kindString, value: "0"}, ttl)
+	}
+
+	val, err := strconv.ParseInt(it.value, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(errNotInteger, "failed to incr cache with key %s!", key)
+	}
+
+	val += value
+	it.value = strconv.FormatInt(val, 10)
+	return val, nil
+}
+
+func (c *memoryClient) HIncrByWithContext(ctx context.Context, key, field string, value int64, ttl time.Duration) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.HIncrBy(key, field, value, ttl)
+}
+
+func (c *memoryClient) HIncrBy(key, field string, value int64, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, err := c.lookup(key, kindHash)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to HIncrBy cache with key %s!", key)
+	}
+
+	if it == nil {
+		it = c.create(key, &item{kind: kindHash, hash: make(map[string]string)}, ttl)
+	}
+
+	var val int64
+	if current, ok := it.hash[field]; ok {
+		if val, err = strconv.ParseInt(current, 10, 64); err != nil {
+			return 0, errors.Wrapf(errNotInteger, "failed to HIncrBy cache with key %s!", key)
+		}
+	}
+
+	val += value
+	it.hash[field] = strconv.FormatInt(val, 10)
+	return val, nil
+}
+
+func (c *memoryClient) PFAddWithContext(ctx context.Context, key string, ttl time.Duration, elements ...interface{}) (bool, error) {
+	if err := alive(ctx); err != nil {
+		return false, err
+	}
+
+	return c.PFAdd(key, ttl, elements...)
+}
+
+// PFAdd keeps the exact set of elements, the count is not an estimate like redis.
+func (c *memoryClient) PFAdd(key string, ttl time.Duration, elements ...interface{}) (bool, error) {
+	members := make([]string, len(elements))
+	for i, e := range elements {
+		member, err := marshal(e)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to PFAdd cache with key %s!", key)
+		}
+		members[i] = member
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, err := c.lookup(key, kindHyperLogLog)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to PFAdd cache with key %s!", key)
+	}
+
+	changed := it == nil
+	if it == nil {
+		it = c.create(key, &item{kind: kindHyperLogLog, hash: make(map[string]string)}, ttl)
+	}
+
+	for _, member := range members {
+		if _, ok := it.hash[member]; !ok {
+			it.hash[member] = ""
+			changed = true
+		}
+	}
+
+	return changed, nil
+}
+
+func (c *memoryClient) PFCountWithContext(ctx context.Context, keys ...string) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.PFCount(keys...)
+}
+
+func (c *memoryClient) PFCount(keys ...string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	union := make(map[string]struct{})
+	for _, key := range keys {
+		it, err := c.lookup(key, kindHyperLogLog)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to PFCount cache with keys %v!", keys)
+		}
+
+		if it != nil {
+			for member := range it.hash {
+				union[member] = struct{}{}
+			}
+		}
+	}
+
+	return int64(len(union)), nil
+}
+
+func (c *memoryClient) SetBitWithContext(ctx context.Context, key string, offset int64, value int, ttl time.Duration) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.SetBit(key, offset, value, ttl)
+}
+
+func (c *memoryClient) SetBit(key string, offset int64, value int, ttl time.Duration) (int64, error) {
+	if offset < 0 || offset >= 1<<32 || (value != 0 && value != 1) {
+		return 0, errors.Wrapf(errBitOffset, "failed to SetBit cache with key %s!", key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, err := c.lookup(key, kindString)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to SetBit cache with key %s!", key)
+	}
+
+	if it == nil {
+		it = c.create(key, &item{kind: kindString}, ttl)
+	}
+
+	b := []byte(it.value)
+	if index := int(offset / 8); index >= len(b) {
+		b = append(b, make([]byte, index-len(b)+1)...)
+	}
+
+	// - redis numbers the bits of a byte from the most significant one
+	mask := byte(1) << uint(7-offset%8)
+	previous := int64(0)
+	if b[offset/8]&mask != 0 {
+		previous = 1
+	}
+
+	if value == 1 {
+		b[offset/8] |= mask
+	} else {
+		b[offset/8] &^= mask
+	}
+
+	it.value = string(b)
+	return previous, nil
+}
+
+func (c *memoryClient) GetBitWithContext(ctx context.Context, key string, offset int64) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.GetBit(key, offset)
+}
+
+func (c *memoryClient) GetBit(key string, offset int64) (int64, error) {
+	if offset < 0 {
+		return 0, errors.Wrapf(errBitOffset, "failed to GetBit cache with key %s!", key)
+	}
+
+	c.mu.Lock()
+	it, err := c.lookup(key, kindString)
+	c.mu.Unlock()
+
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to GetBit cache with key %s!", key)
+	}
+
+	if it == nil || offset/8 >= int64(len(it.value)) {
+		return 0, nil
+	}
+
+	if it.value[offset/8]&(byte(1)<<uint(7-offset%8)) != 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (c *memoryClient) BitCountWithContext(ctx context.Context, key string) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.BitCount(key)
+}
+
+func (c *memoryClient) BitCount(key string) (int64, error) {
+	c.mu.Lock()
+	it, err := c.lookup(key, kindString)
+	c.mu.Unlock()
+
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to BitCount cache with key %s!", key)
+	}
+
+	if it == nil {
+		return 0, nil
+	}
+
+	var count int
+	for i := 0; i < len(it.value); i++ {
+		count += bits.OnesCount8(it.value[i])
+	}
+	return int64(count), nil
+}
+
+// create stores a new item under key with the ttl of its first write.
+// Caller must hold the write lock.
+func (c *memoryClient) create(key string, it *item, ttl time.Duration) *item {
+	if ttl > 0 {
+		it.expireAt = time.Now().Add(ttl)
+	}
+
+	c.items[key] = it
+	return it
+}
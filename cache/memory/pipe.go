@@ -0,0 +1,267 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/codec"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+type (
+	pipe struct {
+		client   *memoryClient
+		mu       sync.Mutex
+		commands []func() error
+	}
+
+	result struct {
+		err error
+	}
+
+	intResult struct {
+		result
+		val int64
+	}
+
+	boolResult struct {
+		result
+		val bool
+	}
+
+	stringMapResult struct {
+		result
+		val map[string]string
+	}
+
+	valueResult struct {
+		result
+		key   string
+		value string
+	}
+)
+
+var errNotInteger = errors.New("ERR value is not an integer or out of range")
+
+func (p *pipe) Set(key string, value interface{}) error {
+	return p.SetWithExpiration(key, value, 0)
+}
+
+func (p *pipe) SetWithExpiration(key string, value interface{}, expired time.Duration) error {
+	val, err := marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set cache with key %s!", key)
+	}
+
+	p.queue(func() error {
+		it := &item{kind: kindString, value: val}
+		if expired > 0 {
+			it.expireAt = time.Now().Add(expired)
+		}
+		p.client.items[key] = it
+		return nil
+	})
+	return nil
+}
+
+func (p *pipe) Get(key string) cache.ValueResult {
+	res := &valueResult{result: result{err: redis.Nil}, key: key}
+	p.queue(func() error {
+		it, err := p.client.lookup(key, kindString)
+		switch {
+		case err != nil:
+			res.err = err
+		case it == nil:
+			res.err = redis.Nil
+		default:
+			res.err, res.value = nil, it.value
+		}
+		return res.err
+	})
+	return res
+}
+
+func (p *pipe) HSet(key, field string, value interface{}) error {
+	val, err := marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to HSet cache with key %s!", key)
+	}
+
+	p.queue(func() error {
+		return p.client.hmset(key, map[string]string{field: val})
+	})
+	return nil
+}
+
+func (p *pipe) HMSet(key string, value map[string]interface{}) error {
+	fields := make(map[string]string, len(value))
+	for field, v := range value {
+		val, err := marshal(v)
+		if err != nil {
+			return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
+		}
+		fields[field] = val
+	}
+
+	p.queue(func() error {
+		return p.client.hmset(key, fields)
+	})
+	return nil
+}
+
+func (p *pipe) HGetAll(key string) cache.StringMapResult {
+	res := &stringMapResult{}
+	p.queue(func() error {
+		it, err := p.client.lookup(key, kindHash)
+		if err != nil {
+			res.err = err
+			return err
+		}
+
+		res.val = make(map[string]string)
+		if it != nil {
+			for field, v := range it.hash {
+				res.val[field] = v
+			}
+		}
+		return nil
+	})
+	return res
+}
+
+func (p *pipe) Del(keys ...string) cache.IntResult {
+	res := &intResult{}
+	p.queue(func() error {
+		for _, key := range keys {
+			if it, ok := p.client.items[key]; ok {
+				if it.expireAt.IsZero() || time.Now().Before(it.expireAt) {
+					res.val++
+				}
+				delete(p.client.items, key)
+			}
+		}
+		return nil
+	})
+	return res
+}
+
+func (p *pipe) Expire(key string, expiration time.Duration) cache.BoolResult {
+	res := &boolResult{}
+	p.queue(func() error {
+		if it, ok := p.client.items[key]; ok && (it.expireAt.IsZero() || time.Now().Before(it.expireAt)) {
+			res.val = true
+		}
+		p.client.expire(key, expiration)
+		return nil
+	})
+	return res
+}
+
+func (p *pipe) Incr(key string) cache.IntResult {
+	res := &intResult{}
+	p.queue(func() error {
+		it, err := p.client.lookup(key, kindString)
+		if err != nil {
+			res.err = err
+			return err
+		}
+
+		if it == nil {
+			it = &item{kind: kindString, value: "0"}
+			p.client.items[key] = it
+		}
+
+		val, err := strconv.ParseInt(it.value, 10, 64)
+		if err != nil {
+			res.err = errNotInteger
+			return res.err
+		}
+
+		res.val = val + 1
+		it.value = strconv.FormatInt(res.val, 10)
+		return nil
+	})
+	return res
+}
+
+func (p *pipe) ZAdd(key string, members ...redis.Z) cache.IntResult {
+	res := &intResult{}
+	p.queue(func() error {
+		res.val, res.err = p.client.zadd(key, members...)
+		return res.err
+	})
+	return res
+}
+
+// Exec applies the queued commands under a single lock, a missing key read by
+// Get is reported on its result rather than failing the whole pipe.
+func (p *pipe) Exec() error {
+	p.mu.Lock()
+	commands := p.commands
+	p.commands = nil
+	p.mu.Unlock()
+
+	p.client.mu.Lock()
+	defer p.client.mu.Unlock()
+
+	var err error
+	for _, command := range commands {
+		if e := command(); e != nil && e != redis.Nil && err == nil {
+			err = e
+		}
+	}
+
+	return errors.Wrapf(err, "failed to exec pipeline")
+}
+
+func (p *pipe) ExecWithContext(ctx context.Context) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return p.Exec()
+}
+
+func (p *pipe) queue(command func() error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.commands = append(p.commands, command)
+}
+
+func (r *result) Err() error {
+	return r.err
+}
+
+func (r *intResult) Val() int64 {
+	return r.val
+}
+
+func (r *boolResult) Val() bool {
+	return r.val
+}
+
+func (r *stringMapResult) Val() map[string]string {
+	return r.val
+}
+
+func (r *valueResult) Scan(object interface{}) error {
+	if r.err == redis.Nil {
+		return errors.Wrapf(r.err, "key %s does not exits", r.key)
+	}
+
+	if r.err != nil {
+		return errors.Wrapf(r.err, "failed to get key %s", r.key)
+	}
+
+	if err := codec.Binary.Unmarshal([]byte(r.value), object); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal object")
+	}
+
+	return nil
+}
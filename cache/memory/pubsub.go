@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	subscription struct {
+		client   *memoryClient
+		channels []string
+		patterns []string
+		messages chan *cache.Message
+		done     chan struct{}
+		once     sync.Once
+		mu       sync.RWMutex
+	}
+)
+
+const bufferSize = 100
+
+func (c *memoryClient) PublishWithContext(ctx context.Context, channel string, message interface{}) error {
+	if err := alive(ctx); err != nil {
+		return err
+	}
+
+	return c.Publish(channel, message)
+}
+
+// Publish delivers message to the live subscriptions, it blocks while the
+// buffer of a subscriber is full.
+func (c *memoryClient) Publish(channel string, message interface{}) error {
+	payload, err := marshal(message)
+	if err != nil {
+		return errors.Wrapf(err, "failed to publish to channel %s!", channel)
+	}
+
+	c.subMu.RLock()
+	subscribers := make([]*subscription, 0, len(c.subscribers))
+	for s := range c.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	c.subMu.RUnlock()
+
+	for _, s := range subscribers {
+		s.deliver(channel, payload)
+	}
+
+	return nil
+}
+
+func (c *memoryClient) Subscribe(channels ...string) (cache.Subscription, error) {
+	return c.subscribe(&subscription{channels: channels})
+}
+
+func (c *memoryClient) PSubscribe(patterns ...string) (cache.Subscription, error) {
+	return c.subscribe(&subscription{patterns: patterns})
+}
+
+func (c *memoryClient) subscribe(s *subscription) (cache.Subscription, error) {
+	if len(s.channels) == 0 && len(s.patterns) == 0 {
+		return nil, errors.New("at least 1 channel is required")
+	}
+
+	s.client = c
+	s.messages = make(chan *cache.Message, bufferSize)
+	s.done = make(chan struct{})
+
+	c.subMu.Lock()
+	c.subscribers[s] = struct{}{}
+	c.subMu.Unlock()
+
+	return s, nil
+}
+
+func (s *subscription) Messages() <-chan *cache.Message {
+	return s.messages
+}
+
+func (s *subscription) Close() error {
+	s.once.Do(func() {
+		s.client.subMu.Lock()
+		delete(s.client.subscribers, s)
+		s.client.subMu.Unlock()
+
+		close(s.done)
+
+		s.mu.Lock()
+		close(s.messages)
+		s.mu.Unlock()
+	})
+
+	return nil
+}
+
+func (s *subscription) deliver(channel, payload string) {
+	for _, c := range s.channels {
+		if c == channel {
+			s.send(&cache.Message{Channel: channel, Payload: payload})
+		}
+	}
+
+	for _, pattern := range s.patterns {
+		if cache.Match(pattern, channel) {
+			s.send(&cache.Message{Channel: channel, Pattern: pattern, Payload: payload})
+		}
+	}
+}
+
+func (s *subscription) send(message *cache.Message) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	select {
+	case s.messages <- message:
+	case <-s.done:
+	}
+}
@@ -0,0 +1,396 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+var errNotFloat = errors.New("ERR min or max is not a float")
+
+func (c *memoryClient) ZAddWithContext(ctx context.Context, key string, members ...redis.Z) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.ZAdd(key, members...)
+}
+
+func (c *memoryClient) ZAdd(key string, members ...redis.Z) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	val, err := c.zadd(key, members...)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to zadd cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *memoryClient) ZIncrByWithContext(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.ZIncrBy(key, increment, member)
+}
+
+func (c *memoryClient) ZIncrBy(key string, increment float64, member string) (float64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, err := c.lookup(key, kindZSet)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to ZIncrBy cache with key %s!", key)
+	}
+
+	score := increment
+	if it != nil {
+		if i := indexOf(it.zset, member); i >= 0 {
+			score += it.zset[i].Score
+		}
+	}
+
+	if _, err := c.zadd(key, redis.Z{Score: score, Member: member}); err != nil {
+		return 0, errors.Wrapf(err, "failed to ZIncrBy cache with key %s!", key)
+	}
+	return score, nil
+}
+
+func (c *memoryClient) ZRemWithContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.ZRem(key, members...)
+}
+
+func (c *memoryClient) ZRem(key string, members ...interface{}) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, err := c.lookup(key, kindZSet)
+	if err != nil || it == nil {
+		return 0, errors.Wrapf(err, "failed to ZRem cache with key %s!", key)
+	}
+
+	var removed int64
+	for _, m := range members {
+		member, err := marshal(m)
+		if err != nil {
+			return removed, errors.Wrapf(err, "failed to ZRem cache with key %s!", key)
+		}
+
+		if i := indexOf(it.zset, member); i >= 0 {
+			it.zset = append(it.zset[:i], it.zset[i+1:]...)
+			removed++
+		}
+	}
+
+	if len(it.zset) == 0 {
+		delete(c.items, key)
+	}
+	return removed, nil
+}
+
+func (c *memoryClient) ZRemRangeByRankWithContext(ctx context.Context, key string, start, stop int64) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.ZRemRangeByRank(key, start, stop)
+}
+
+func (c *memoryClient) ZRemRangeByRank(key string, start, stop int64) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, err := c.lookup(key, kindZSet)
+	if err != nil || it == nil {
+		return 0, errors.Wrapf(err, "failed to ZRemRangeByRank cache with key %s!", key)
+	}
+
+	lo, hi, ok := rankRange(len(it.zset), start, stop)
+	if !ok {
+		return 0, nil
+	}
+
+	it.zset = append(it.zset[:lo], it.zset[hi+1:]...)
+	if len(it.zset) == 0 {
+		delete(c.items, key)
+	}
+	return int64(hi - lo + 1), nil
+}
+
+func (c *memoryClient) ZRangeWithContext(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	if err := alive(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.ZRange(key, start, stop)
+}
+
+func (c *memoryClient) ZRange(key string, start, stop int64) ([]redis.Z, error) {
+	zset, err := c.zset(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to zrange cache with key %s!", key)
+	}
+
+	lo, hi, ok := rankRange(len(zset), start, stop)
+	if !ok {
+		return []redis.Z{}, nil
+	}
+	return zset[lo : hi+1], nil
+}
+
+func (c *memoryClient) ZRevRangeWithContext(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	if err := alive(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.ZRevRange(key, start, stop)
+}
+
+func (c *memoryClient) ZRevRange(key string, start, stop int64) ([]redis.Z, error) {
+	zset, err := c.zset(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to ZRevRange cache with key %s!", key)
+	}
+
+	reverse(zset)
+	lo, hi, ok := rankRange(len(zset), start, stop)
+	if !ok {
+		return []redis.Z{}, nil
+	}
+	return zset[lo : hi+1], nil
+}
+
+func (c *memoryClient) ZRangeByScoreWithContext(ctx context.Context, key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	if err := alive(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.ZRangeByScore(key, opt)
+}
+
+func (c *memoryClient) ZRangeByScore(key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	zset, err := c.zset(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to ZRangeByScore cache with key %s!", key)
+	}
+
+	val, err := byScore(zset, opt)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to ZRangeByScore cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *memoryClient) ZRevRangeByScoreWithContext(ctx context.Context, key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	if err := alive(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.ZRevRangeByScore(key, opt)
+}
+
+func (c *memoryClient) ZRevRangeByScore(key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	zset, err := c.zset(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to ZRevRangeByScore cache with key %s!", key)
+	}
+
+	reverse(zset)
+	val, err := byScore(zset, opt)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to ZRevRangeByScore cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *memoryClient) ZRankWithContext(ctx context.Context, key, member string) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.ZRank(key, member)
+}
+
+func (c *memoryClient) ZRank(key, member string) (int64, error) {
+	zset, err := c.zset(key)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to ZRank cache with key %s!", key)
+	}
+
+	i := indexOf(zset, member)
+	if i < 0 {
+		return 0, errors.Wrapf(redis.Nil, "failed to ZRank cache with key %s!", key)
+	}
+	return int64(i), nil
+}
+
+func (c *memoryClient) ZRevRankWithContext(ctx context.Context, key, member string) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.ZRevRank(key, member)
+}
+
+func (c *memoryClient) ZRevRank(key, member string) (int64, error) {
+	zset, err := c.zset(key)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to ZRevRank cache with key %s!", key)
+	}
+
+	i := indexOf(zset, member)
+	if i < 0 {
+		return 0, errors.Wrapf(redis.Nil, "failed to ZRevRank cache with key %s!", key)
+	}
+	return int64(len(zset) - 1 - i), nil
+}
+
+func (c *memoryClient) ZScoreWithContext(ctx context.Context, key, member string) (float64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.ZScore(key, member)
+}
+
+func (c *memoryClient) ZScore(key, member string) (float64, error) {
+	zset, err := c.zset(key)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to ZScore cache with key %s!", key)
+	}
+
+	i := indexOf(zset, member)
+	if i < 0 {
+		return 0, errors.Wrapf(redis.Nil, "failed to ZScore cache with key %s!", key)
+	}
+	return zset[i].Score, nil
+}
+
+func (c *memoryClient) ZCardWithContext(ctx context.Context, key string) (int64, error) {
+	if err := alive(ctx); err != nil {
+		return 0, err
+	}
+
+	return c.ZCard(key)
+}
+
+func (c *memoryClient) ZCard(key string) (int64, error) {
+	zset, err := c.zset(key)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to ZCard cache with key %s!", key)
+	}
+	return int64(len(zset)), nil
+}
+
+// zset returns a copy of the members stored under key, sorted by score.
+func (c *memoryClient) zset(key string) ([]redis.Z, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, err := c.lookup(key, kindZSet)
+	if err != nil || it == nil {
+		return []redis.Z{}, err
+	}
+
+	zset := make([]redis.Z, len(it.zset))
+	copy(zset, it.zset)
+	return zset, nil
+}
+
+func indexOf(zset []redis.Z, member string) int {
+	for i, z := range zset {
+		if z.Member.(string) == member {
+			return i
+		}
+	}
+	return -1
+}
+
+func reverse(zset []redis.Z) {
+	for i, j := 0, len(zset)-1; i < j; i, j = i+1, j-1 {
+		zset[i], zset[j] = zset[j], zset[i]
+	}
+}
+
+// rankRange converts the inclusive redis ranks into slice indexes of a set of size n.
+func rankRange(n int, start, stop int64) (int, int, bool) {
+	if start < 0 {
+		start += int64(n)
+	}
+	if stop < 0 {
+		stop += int64(n)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= int64(n) {
+		stop = int64(n) - 1
+	}
+	if start > stop || start >= int64(n) {
+		return 0, 0, false
+	}
+	return int(start), int(stop), true
+}
+
+// byScore filters zset, already in the iteration order, with the bounds and limit of opt.
+func byScore(zset []redis.Z, opt redis.ZRangeBy) ([]redis.Z, error) {
+	min, minExclusive, err := parseBound(opt.Min)
+	if err != nil {
+		return nil, err
+	}
+
+	max, maxExclusive, err := parseBound(opt.Max)
+	if err != nil {
+		return nil, err
+	}
+
+	val := make([]redis.Z, 0)
+	for _, z := range zset {
+		if z.Score < min || (minExclusive && z.Score == min) || z.Score > max || (maxExclusive && z.Score == max) {
+			continue
+		}
+		val = append(val, z)
+	}
+
+	if opt.Offset == 0 && opt.Count == 0 {
+		return val, nil
+	}
+
+	if opt.Offset < 0 || opt.Offset >= int64(len(val)) {
+		return []redis.Z{}, nil
+	}
+
+	val = val[opt.Offset:]
+	if opt.Count >= 0 && opt.Count < int64(len(val)) {
+		val = val[:opt.Count]
+	}
+	return val, nil
+}
+
+// parseBound reads a score bound such as "-inf", "(1.5" or "10".
+func parseBound(bound string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(bound, "(")
+	bound = strings.TrimPrefix(bound, "(")
+
+	switch bound {
+	case "-inf":
+		return math.Inf(-1), exclusive, nil
+	case "+inf", "inf":
+		return math.Inf(1), exclusive, nil
+	}
+
+	val, err := strconv.ParseFloat(bound, 64)
+	if err != nil {
+		return 0, false, errNotFloat
+	}
+	return val, exclusive, nil
+}
@@ -0,0 +1,288 @@
+package namespace
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/codec"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+// FlushCount is the number of keys removed per loop when flushing a namespace.
+const FlushCount = 1000
+
+type (
+	Option struct {
+		// Prefix is prepended to every key, e.g. "billing:".
+		Prefix string
+	}
+
+	namespaceClient struct {
+		option  *Option
+		remote  cache.Cache
+		pattern string
+	}
+)
+
+func getOption(option *Option) error {
+	if option.Prefix == "" {
+		return errors.New("prefix is required")
+	}
+
+	return nil
+}
+
+// New wraps remote so every key is transparently stored under the prefix of
+// the option, flushing only removes the keys of the namespace. The counter,
+// sorted set, scanner and pub/sub interfaces of remote are forwarded, the redis
+// instance is not: lock, ratelimit, bloom, tag and redisstream reject the
+// namespaced client and take their own Prefix option.
+func New(remote cache.Cache, option *Option) (cache.Cache, error) {
+	if remote == nil {
+		return nil, errors.New("remote cache is required")
+	}
+
+	if err := getOption(option); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &namespaceClient{option: option, remote: remote, pattern: escape(option.Prefix)}, nil
+}
+
+func (c *namespaceClient) PingWithContext(ctx context.Context) error {
+	return c.remote.PingWithContext(ctx)
+}
+
+func (c *namespaceClient) Ping() error {
+	return c.PingWithContext(context.Background())
+}
+
+func (c *namespaceClient) SetWithExpirationWithContext(ctx context.Context, key string, value interface{}, duration time.Duration) error {
+	return c.remote.SetWithExpirationWithContext(ctx, c.key(key), value, duration)
+}
+
+func (c *namespaceClient) SetWithExpiration(key string, value interface{}, duration time.Duration) error {
+	return c.SetWithExpirationWithContext(context.Background(), key, value, duration)
+}
+
+func (c *namespaceClient) SetWithContext(ctx context.Context, key string, value interface{}) error {
+	return c.remote.SetWithContext(ctx, c.key(key), value)
+}
+
+func (c *namespaceClient) Set(key string, value interface{}) error {
+	return c.SetWithContext(context.Background(), key, value)
+}
+
+func (c *namespaceClient) GetWithContext(ctx context.Context, key string, data interface{}) error {
+	return c.remote.GetWithContext(ctx, c.key(key), data)
+}
+
+func (c *namespaceClient) Get(key string, data interface{}) error {
+	return c.GetWithContext(context.Background(), key, data)
+}
+
+func (c *namespaceClient) SetZSetWithExpirationWithContext(ctx context.Context, key string, duration time.Duration, data ...redis.Z) error {
+	return c.remote.SetZSetWithExpirationWithContext(ctx, c.key(key), duration, data...)
+}
+
+func (c *namespaceClient) SetZSetWithExpiration(key string, duration time.Duration, data ...redis.Z) error {
+	return c.SetZSetWithExpirationWithContext(context.Background(), key, duration, data...)
+}
+
+func (c *namespaceClient) SetZSetWithContext(ctx context.Context, key string, data ...redis.Z) error {
+	return c.remote.SetZSetWithContext(ctx, c.key(key), data...)
+}
+
+func (c *namespaceClient) SetZSet(key string, data ...redis.Z) error {
+	return c.SetZSetWithContext(context.Background(), key, data...)
+}
+
+func (c *namespaceClient) GetZSetWithContext(ctx context.Context, key string) ([]redis.Z, error) {
+	return c.remote.GetZSetWithContext(ctx, c.key(key))
+}
+
+func (c *namespaceClient) GetZSet(key string) ([]redis.Z, error) {
+	return c.GetZSetWithContext(context.Background(), key)
+}
+
+func (c *namespaceClient) HMSetWithExpirationWithContext(ctx context.Context, key string, value map[string]interface{}, ttl time.Duration) error {
+	return c.remote.HMSetWithExpirationWithContext(ctx, c.key(key), value, ttl)
+}
+
+func (c *namespaceClient) HMSetWithExpiration(key string, value map[string]interface{}, ttl time.Duration) error {
+	return c.HMSetWithExpirationWithContext(context.Background(), key, value, ttl)
+}
+
+func (c *namespaceClient) HMSetWithContext(ctx context.Context, key string, value map[string]interface{}) error {
+	return c.remote.HMSetWithContext(ctx, c.key(key), value)
+}
+
+func (c *namespaceClient) HMSet(key string, value map[string]interface{}) error {
+	return c.HMSetWithContext(context.Background(), key, value)
+}
+
+func (c *namespaceClient) HSetWithExpirationWithContext(ctx context.Context, key, field string, value interface{}, ttl time.Duration) error {
+	return c.remote.HSetWithExpirationWithContext(ctx, c.key(key), field, value, ttl)
+}
+
+func (c *namespaceClient) HSetWithExpiration(key, field string, value interface{}, ttl time.Duration) error {
+	return c.HSetWithExpirationWithContext(context.Background(), key, field, value, ttl)
+}
+
+func (c *namespaceClient) HSetWithContext(ctx context.Context, key, field string, value interface{}) error {
+	return c.remote.HSetWithContext(ctx, c.key(key), field, value)
+}
+
+func (c *namespaceClient) HSet(key, field string, value interface{}) error {
+	return c.HSetWithContext(context.Background(), key, field, value)
+}
+
+func (c *namespaceClient) HMGetWithContext(ctx context.Context, key string, fields ...string) ([]interface{}, error) {
+	return c.remote.HMGetWithContext(ctx, c.key(key), fields...)
+}
+
+func (c *namespaceClient) HMGet(key string, fields ...string) ([]interface{}, error) {
+	return c.HMGetWithContext(context.Background(), key, fields...)
+}
+
+func (c *namespaceClient) HGetAllWithContext(ctx context.Context, key string) (map[string]string, error) {
+	return c.remote.HGetAllWithContext(ctx, c.key(key))
+}
+
+func (c *namespaceClient) HGetAll(key string) (map[string]string, error) {
+	return c.HGetAllWithContext(context.Background(), key)
+}
+
+func (c *namespaceClient) HGetWithContext(ctx context.Context, key, field string, response interface{}) error {
+	return c.remote.HGetWithContext(ctx, c.key(key), field, response)
+}
+
+func (c *namespaceClient) HGet(key, field string, response interface{}) error {
+	return c.HGetWithContext(context.Background(), key, field, response)
+}
+
+func (c *namespaceClient) MGetWithContext(ctx context.Context, key []string) ([]interface{}, error) {
+	return c.remote.MGetWithContext(ctx, c.keys(key))
+}
+
+func (c *namespaceClient) MGet(key []string) ([]interface{}, error) {
+	return c.MGetWithContext(context.Background(), key)
+}
+
+// KeysWithContext returns the keys of the namespace matching pattern, without the prefix.
+func (c *namespaceClient) KeysWithContext(ctx context.Context, pattern string) ([]string, error) {
+	keys, err := c.remote.KeysWithContext(ctx, c.pattern+pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, c.option.Prefix)
+	}
+
+	return keys, nil
+}
+
+func (c *namespaceClient) Keys(pattern string) ([]string, error) {
+	return c.KeysWithContext(context.Background(), pattern)
+}
+
+func (c *namespaceClient) RemoveWithContext(ctx context.Context, key string) error {
+	return c.remote.RemoveWithContext(ctx, c.key(key))
+}
+
+func (c *namespaceClient) Remove(key string) error {
+	return c.RemoveWithContext(context.Background(), key)
+}
+
+func (c *namespaceClient) RemoveByPatternWithContext(ctx context.Context, pattern string, countPerLoop int64) error {
+	return c.remote.RemoveByPatternWithContext(ctx, c.pattern+pattern, countPerLoop)
+}
+
+func (c *namespaceClient) RemoveByPattern(pattern string, countPerLoop int64) error {
+	return c.RemoveByPatternWithContext(context.Background(), pattern, countPerLoop)
+}
+
+// FlushDatabaseWithContext removes the keys of the namespace, the other keys of the database are kept.
+func (c *namespaceClient) FlushDatabaseWithContext(ctx context.Context) error {
+	return c.RemoveByPatternWithContext(ctx, "*", FlushCount)
+}
+
+func (c *namespaceClient) FlushDatabase() error {
+	return c.FlushDatabaseWithContext(context.Background())
+}
+
+// FlushAllWithContext removes the keys of the namespace like FlushDatabaseWithContext.
+func (c *namespaceClient) FlushAllWithContext(ctx context.Context) error {
+	return c.FlushDatabaseWithContext(ctx)
+}
+
+func (c *namespaceClient) FlushAll() error {
+	return c.FlushAllWithContext(context.Background())
+}
+
+func (c *namespaceClient) Close() error {
+	return c.remote.Close()
+}
+
+func (c *namespaceClient) Client() cache.Cache {
+	return c
+}
+
+func (c *namespaceClient) PipelineWithContext(ctx context.Context) cache.Pipe {
+	return &pipe{client: c, instance: c.remote.PipelineWithContext(ctx)}
+}
+
+func (c *namespaceClient) Pipeline() cache.Pipe {
+	return c.PipelineWithContext(context.Background())
+}
+
+func (c *namespaceClient) TxPipelineWithContext(ctx context.Context) cache.Pipe {
+	return &pipe{client: c, instance: c.remote.TxPipelineWithContext(ctx)}
+}
+
+func (c *namespaceClient) TxPipeline() cache.Pipe {
+	return c.TxPipelineWithContext(context.Background())
+}
+
+func (c *namespaceClient) Decode(value interface{}, object interface{}) error {
+	if decoder, ok := c.remote.(cache.Decoder); ok {
+		return decoder.Decode(value, object)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return redis.Nil
+	}
+
+	return codec.Binary.Unmarshal([]byte(s), object)
+}
+
+func (c *namespaceClient) key(key string) string {
+	return c.option.Prefix + key
+}
+
+func (c *namespaceClient) keys(keys []string) []string {
+	val := make([]string, len(keys))
+	for i, key := range keys {
+		val[i] = c.key(key)
+	}
+	return val
+}
+
+// escape quotes the glob characters of prefix so it only matches itself in a pattern.
+func escape(prefix string) string {
+	var b strings.Builder
+	for _, r := range prefix {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
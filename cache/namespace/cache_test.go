@@ -0,0 +1,128 @@
+package namespace
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/memory"
+)
+
+func Test_Keys_strips_prefix(t *testing.T) {
+	remote := memory.New()
+	c, err := New(remote, &Option{Prefix: "billing:"})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	_ = c.Set("user:1", "a")
+	_ = remote.Set("user:2", "b")
+
+	keys, err := c.Keys("user:*")
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if len(keys) != 1 || keys[0] != "user:1" {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+}
+
+func Test_FlushDatabase_is_scoped_to_prefix(t *testing.T) {
+	remote := memory.New()
+	c, _ := New(remote, &Option{Prefix: "tenant[1]:"})
+
+	_ = c.Set("key", "a")
+	_ = remote.Set("tenant1:key", "b")
+	_ = remote.Set("other", "c")
+
+	if err := c.FlushDatabase(); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	keys, _ := remote.Keys("*")
+	if len(keys) != 2 || keys[0] != "other" || keys[1] != "tenant1:key" {
+		t.Errorf("unexpected remaining keys %+v", keys)
+	}
+}
+
+func Test_Pipeline_prefixes_keys(t *testing.T) {
+	remote := memory.New()
+	c, _ := New(remote, &Option{Prefix: "svc:"})
+
+	p := c.Pipeline()
+	incr := p.Incr("counter")
+	if err := p.Exec(); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	values, _ := remote.MGet([]string{"svc:counter"})
+	if incr.Val() != 1 || values[0] != "1" {
+		t.Errorf("unexpected pipeline result %d %+v", incr.Val(), values)
+	}
+}
+
+func Test_Counter_prefixes_keys(t *testing.T) {
+	remote := memory.New()
+	c, _ := New(remote, &Option{Prefix: "svc:"})
+
+	if _, err := c.(cache.Counter).IncrBy("counter", 2, time.Minute); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	values, _ := remote.MGet([]string{"svc:counter"})
+	if values[0] != "2" {
+		t.Errorf("unexpected counter %+v", values)
+	}
+}
+
+func Test_ScanKeys_strips_prefix(t *testing.T) {
+	remote := memory.New()
+	c, _ := New(remote, &Option{Prefix: "svc:"})
+
+	_ = c.Set("user:1", "a")
+	_ = remote.Set("user:2", "b")
+
+	keys, err := c.(cache.Scanner).ScanKeys("user:*", 10)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if len(keys) != 1 || keys[0] != "user:1" {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+}
+
+func Test_PSubscribe_is_scoped_to_prefix(t *testing.T) {
+	remote := memory.New()
+	c, _ := New(remote, &Option{Prefix: "svc:"})
+	ps := c.(cache.PubSub)
+
+	s, err := ps.PSubscribe("events.*")
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+	defer s.Close()
+
+	_ = remote.(cache.PubSub).Publish("events.other", "skipped")
+	if err := ps.Publish("events.created", "payload"); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	select {
+	case msg := <-s.Messages():
+		if msg.Channel != "events.created" || msg.Pattern != "events.*" || msg.Payload != "payload" {
+			t.Errorf("unexpected message %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected a message")
+	}
+}
+
+func Test_Instance_is_not_forwarded(t *testing.T) {
+	c, _ := New(memory.New(), &Option{Prefix: "svc:"})
+
+	if _, ok := c.(cache.Instance); ok {
+		t.Error("redis instance should not be forwarded")
+	}
+}
@@ -0,0 +1,437 @@
+package namespace
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+// The companion interfaces are forwarded to the remote cache with the keys and
+// channels under the prefix, they fail when it does not implement them.
+
+func (c *namespaceClient) counter() (cache.Counter, error) {
+	counter, ok := c.remote.(cache.Counter)
+	if !ok {
+		return nil, errors.New("remote cache does not support counters")
+	}
+	return counter, nil
+}
+
+func (c *namespaceClient) sortedSet() (cache.SortedSet, error) {
+	sortedSet, ok := c.remote.(cache.SortedSet)
+	if !ok {
+		return nil, errors.New("remote cache does not support sorted sets")
+	}
+	return sortedSet, nil
+}
+
+func (c *namespaceClient) scanner() (cache.Scanner, error) {
+	scanner, ok := c.remote.(cache.Scanner)
+	if !ok {
+		return nil, errors.New("remote cache does not support scans")
+	}
+	return scanner, nil
+}
+
+func (c *namespaceClient) pubSub() (cache.PubSub, error) {
+	ps, ok := c.remote.(cache.PubSub)
+	if !ok {
+		return nil, errors.New("remote cache does not support pub/sub")
+	}
+	return ps, nil
+}
+
+func (c *namespaceClient) IncrWithContext(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(ctx, c.key(key), 1, ttl)
+}
+
+func (c *namespaceClient) Incr(key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(context.Background(), key, 1, ttl)
+}
+
+func (c *namespaceClient) DecrWithContext(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(ctx, c.key(key), -1, ttl)
+}
+
+func (c *namespaceClient) Decr(key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(context.Background(), key, -1, ttl)
+}
+
+func (c *namespaceClient) IncrByWithContext(ctx context.Context, key string, value int64, ttl time.Duration) (int64, error) {
+	counter, err := c.counter()
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.IncrByWithContext(ctx, c.key(key), value, ttl)
+}
+
+func (c *namespaceClient) IncrBy(key string, value int64, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(context.Background(), key, value, ttl)
+}
+
+func (c *namespaceClient) HIncrByWithContext(ctx context.Context, key, field string, value int64, ttl time.Duration) (int64, error) {
+	counter, err := c.counter()
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.HIncrByWithContext(ctx, c.key(key), field, value, ttl)
+}
+
+func (c *namespaceClient) HIncrBy(key, field string, value int64, ttl time.Duration) (int64, error) {
+	return c.HIncrByWithContext(context.Background(), key, field, value, ttl)
+}
+
+func (c *namespaceClient) PFAddWithContext(ctx context.Context, key string, ttl time.Duration, elements ...interface{}) (bool, error) {
+	counter, err := c.counter()
+	if err != nil {
+		return false, err
+	}
+
+	return counter.PFAddWithContext(ctx, c.key(key), ttl, elements...)
+}
+
+func (c *namespaceClient) PFAdd(key string, ttl time.Duration, elements ...interface{}) (bool, error) {
+	return c.PFAddWithContext(context.Background(), key, ttl, elements...)
+}
+
+func (c *namespaceClient) PFCountWithContext(ctx context.Context, keys ...string) (int64, error) {
+	counter, err := c.counter()
+	if err != nil {
+		return 0, err
+	}
+	return counter.PFCountWithContext(ctx, c.keys(keys)...)
+}
+
+func (c *namespaceClient) PFCount(keys ...string) (int64, error) {
+	return c.PFCountWithContext(context.Background(), keys...)
+}
+
+func (c *namespaceClient) SetBitWithContext(ctx context.Context, key string, offset int64, value int, ttl time.Duration) (int64, error) {
+	counter, err := c.counter()
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.SetBitWithContext(ctx, c.key(key), offset, value, ttl)
+}
+
+func (c *namespaceClient) SetBit(key string, offset int64, value int, ttl time.Duration) (int64, error) {
+	return c.SetBitWithContext(context.Background(), key, offset, value, ttl)
+}
+
+func (c *namespaceClient) GetBitWithContext(ctx context.Context, key string, offset int64) (int64, error) {
+	counter, err := c.counter()
+	if err != nil {
+		return 0, err
+	}
+	return counter.GetBitWithContext(ctx, c.key(key), offset)
+}
+
+func (c *namespaceClient) GetBit(key string, offset int64) (int64, error) {
+	return c.GetBitWithContext(context.Background(), key, offset)
+}
+
+func (c *namespaceClient) BitCountWithContext(ctx context.Context, key string) (int64, error) {
+	counter, err := c.counter()
+	if err != nil {
+		return 0, err
+	}
+	return counter.BitCountWithContext(ctx, c.key(key))
+}
+
+func (c *namespaceClient) BitCount(key string) (int64, error) {
+	return c.BitCountWithContext(context.Background(), key)
+}
+
+func (c *namespaceClient) ZAddWithContext(ctx context.Context, key string, members ...redis.Z) (int64, error) {
+	sortedSet, err := c.sortedSet()
+	if err != nil {
+		return 0, err
+	}
+
+	return sortedSet.ZAddWithContext(ctx, c.key(key), members...)
+}
+
+func (c *namespaceClient) ZAdd(key string, members ...redis.Z) (int64, error) {
+	return c.ZAddWithContext(context.Background(), key, members...)
+}
+
+func (c *namespaceClient) ZIncrByWithContext(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	sortedSet, err := c.sortedSet()
+	if err != nil {
+		return 0, err
+	}
+
+	return sortedSet.ZIncrByWithContext(ctx, c.key(key), increment, member)
+}
+
+func (c *namespaceClient) ZIncrBy(key string, increment float64, member string) (float64, error) {
+	return c.ZIncrByWithContext(context.Background(), key, increment, member)
+}
+
+func (c *namespaceClient) ZRemWithContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	sortedSet, err := c.sortedSet()
+	if err != nil {
+		return 0, err
+	}
+
+	return sortedSet.ZRemWithContext(ctx, c.key(key), members...)
+}
+
+func (c *namespaceClient) ZRem(key string, members ...interface{}) (int64, error) {
+	return c.ZRemWithContext(context.Background(), key, members...)
+}
+
+func (c *namespaceClient) ZRemRangeByRankWithContext(ctx context.Context, key string, start, stop int64) (int64, error) {
+	sortedSet, err := c.sortedSet()
+	if err != nil {
+		return 0, err
+	}
+
+	return sortedSet.ZRemRangeByRankWithContext(ctx, c.key(key), start, stop)
+}
+
+func (c *namespaceClient) ZRemRangeByRank(key string, start, stop int64) (int64, error) {
+	return c.ZRemRangeByRankWithContext(context.Background(), key, start, stop)
+}
+
+func (c *namespaceClient) ZRangeWithContext(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	sortedSet, err := c.sortedSet()
+	if err != nil {
+		return nil, err
+	}
+	return sortedSet.ZRangeWithContext(ctx, c.key(key), start, stop)
+}
+
+func (c *namespaceClient) ZRange(key string, start, stop int64) ([]redis.Z, error) {
+	return c.ZRangeWithContext(context.Background(), key, start, stop)
+}
+
+func (c *namespaceClient) ZRevRangeWithContext(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	sortedSet, err := c.sortedSet()
+	if err != nil {
+		return nil, err
+	}
+	return sortedSet.ZRevRangeWithContext(ctx, c.key(key), start, stop)
+}
+
+func (c *namespaceClient) ZRevRange(key string, start, stop int64) ([]redis.Z, error) {
+	return c.ZRevRangeWithContext(context.Background(), key, start, stop)
+}
+
+func (c *namespaceClient) ZRangeByScoreWithContext(ctx context.Context, key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	sortedSet, err := c.sortedSet()
+	if err != nil {
+		return nil, err
+	}
+	return sortedSet.ZRangeByScoreWithContext(ctx, c.key(key), opt)
+}
+
+func (c *namespaceClient) ZRangeByScore(key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	return c.ZRangeByScoreWithContext(context.Background(), key, opt)
+}
+
+func (c *namespaceClient) ZRevRangeByScoreWithContext(ctx context.Context, key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	sortedSet, err := c.sortedSet()
+	if err != nil {
+		return nil, err
+	}
+	return sortedSet.ZRevRangeByScoreWithContext(ctx, c.key(key), opt)
+}
+
+func (c *namespaceClient) ZRevRangeByScore(key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	return c.ZRevRangeByScoreWithContext(context.Background(), key, opt)
+}
+
+func (c *namespaceClient) ZRankWithContext(ctx context.Context, key, member string) (int64, error) {
+	sortedSet, err := c.sortedSet()
+	if err != nil {
+		return 0, err
+	}
+	return sortedSet.ZRankWithContext(ctx, c.key(key), member)
+}
+
+func (c *namespaceClient) ZRank(key, member string) (int64, error) {
+	return c.ZRankWithContext(context.Background(), key, member)
+}
+
+func (c *namespaceClient) ZRevRankWithContext(ctx context.Context, key, member string) (int64, error) {
+	sortedSet, err := c.sortedSet()
+	if err != nil {
+		return 0, err
+	}
+	return sortedSet.ZRevRankWithContext(ctx, c.key(key), member)
+}
+
+func (c *namespaceClient) ZRevRank(key, member string) (int64, error) {
+	return c.ZRevRankWithContext(context.Background(), key, member)
+}
+
+func (c *namespaceClient) ZScoreWithContext(ctx context.Context, key, member string) (float64, error) {
+	sortedSet, err := c.sortedSet()
+	if err != nil {
+		return 0, err
+	}
+	return sortedSet.ZScoreWithContext(ctx, c.key(key), member)
+}
+
+func (c *namespaceClient) ZScore(key, member string) (float64, error) {
+	return c.ZScoreWithContext(context.Background(), key, member)
+}
+
+func (c *namespaceClient) ZCardWithContext(ctx context.Context, key string) (int64, error) {
+	sortedSet, err := c.sortedSet()
+	if err != nil {
+		return 0, err
+	}
+	return sortedSet.ZCardWithContext(ctx, c.key(key))
+}
+
+func (c *namespaceClient) ZCard(key string) (int64, error) {
+	return c.ZCardWithContext(context.Background(), key)
+}
+
+// ScanKeysWithContext returns the keys of the namespace matching pattern, without the prefix.
+func (c *namespaceClient) ScanKeysWithContext(ctx context.Context, pattern string, count int64) ([]string, error) {
+	scanner, err := c.scanner()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := scanner.ScanKeysWithContext(ctx, c.pattern+pattern, count)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, c.option.Prefix)
+	}
+
+	return keys, nil
+}
+
+func (c *namespaceClient) ScanKeys(pattern string, count int64) ([]string, error) {
+	return c.ScanKeysWithContext(context.Background(), pattern, count)
+}
+
+func (c *namespaceClient) UnlinkByPatternWithContext(ctx context.Context, pattern string, count int64) (int64, error) {
+	scanner, err := c.scanner()
+	if err != nil {
+		return 0, err
+	}
+	return scanner.UnlinkByPatternWithContext(ctx, c.pattern+pattern, count)
+}
+
+func (c *namespaceClient) UnlinkByPattern(pattern string, count int64) (int64, error) {
+	return c.UnlinkByPatternWithContext(context.Background(), pattern, count)
+}
+
+func (c *namespaceClient) PublishWithContext(ctx context.Context, channel string, message interface{}) error {
+	ps, err := c.pubSub()
+	if err != nil {
+		return err
+	}
+	return ps.PublishWithContext(ctx, c.key(channel), message)
+}
+
+func (c *namespaceClient) Publish(channel string, message interface{}) error {
+	return c.PublishWithContext(context.Background(), channel, message)
+}
+
+// Subscribe listens to channels of the namespace, the messages are delivered
+// with the channel without the prefix.
+func (c *namespaceClient) Subscribe(channels ...string) (cache.Subscription, error) {
+	ps, err := c.pubSub()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := ps.Subscribe(c.keys(channels)...)
+	if err != nil {
+		return nil, err
+	}
+	return newSubscription(s, c.option.Prefix), nil
+}
+
+// PSubscribe listens to the channels of the namespace matching patterns, the
+// messages are delivered with the channel and pattern without the prefix.
+func (c *namespaceClient) PSubscribe(patterns ...string) (cache.Subscription, error) {
+	ps, err := c.pubSub()
+	if err != nil {
+		return nil, err
+	}
+
+	escaped := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		escaped[i] = c.pattern + pattern
+	}
+
+	s, err := ps.PSubscribe(escaped...)
+	if err != nil {
+		return nil, err
+	}
+	return newSubscription(s, c.option.Prefix), nil
+}
+
+type subscription struct {
+	instance cache.Subscription
+	prefix   string
+	pattern  string
+	messages chan *cache.Message
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newSubscription(instance cache.Subscription, prefix string) *subscription {
+	s := &subscription{
+		instance: instance,
+		prefix:   prefix,
+		pattern:  escape(prefix),
+		messages: make(chan *cache.Message),
+		done:     make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *subscription) Messages() <-chan *cache.Message {
+	return s.messages
+}
+
+func (s *subscription) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		err = s.instance.Close()
+	})
+
+	return err
+}
+
+func (s *subscription) run() {
+	defer close(s.messages)
+
+	for msg := range s.instance.Messages() {
+		message := &cache.Message{
+			Channel: strings.TrimPrefix(msg.Channel, s.prefix),
+			Pattern: strings.TrimPrefix(msg.Pattern, s.pattern),
+			Payload: msg.Payload,
+		}
+
+		select {
+		case s.messages <- message:
+		case <-s.done:
+			return
+		}
+	}
+}
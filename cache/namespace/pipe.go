@@ -0,0 +1,65 @@
+package namespace
+
+import (
+	"context"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+)
+
+type (
+	pipe struct {
+		client   *namespaceClient
+		instance cache.Pipe
+	}
+)
+
+func (p *pipe) Set(key string, value interface{}) error {
+	return p.SetWithExpiration(key, value, 0)
+}
+
+func (p *pipe) SetWithExpiration(key string, value interface{}, expired time.Duration) error {
+	return p.instance.SetWithExpiration(p.client.key(key), value, expired)
+}
+
+func (p *pipe) Get(key string) cache.ValueResult {
+	return p.instance.Get(p.client.key(key))
+}
+
+func (p *pipe) HSet(key, field string, value interface{}) error {
+	return p.instance.HSet(p.client.key(key), field, value)
+}
+
+func (p *pipe) HMSet(key string, value map[string]interface{}) error {
+	return p.instance.HMSet(p.client.key(key), value)
+}
+
+func (p *pipe) HGetAll(key string) cache.StringMapResult {
+	return p.instance.HGetAll(p.client.key(key))
+}
+
+func (p *pipe) Del(keys ...string) cache.IntResult {
+	return p.instance.Del(p.client.keys(keys)...)
+}
+
+func (p *pipe) Expire(key string, expiration time.Duration) cache.BoolResult {
+	return p.instance.Expire(p.client.key(key), expiration)
+}
+
+func (p *pipe) Incr(key string) cache.IntResult {
+	return p.instance.Incr(p.client.key(key))
+}
+
+func (p *pipe) ZAdd(key string, members ...redis.Z) cache.IntResult {
+	return p.instance.ZAdd(p.client.key(key), members...)
+}
+
+func (p *pipe) ExecWithContext(ctx context.Context) error {
+	return p.instance.ExecWithContext(ctx)
+}
+
+func (p *pipe) Exec() error {
+	return p.ExecWithContext(context.Background())
+}
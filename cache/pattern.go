@@ -0,0 +1,90 @@
+package cache
+
+// Match reports whether key matches the glob-style pattern using the same rules
+// as the redis KEYS and SCAN commands: '*', '?', '[...]' classes with ranges and
+// '^' negation, and '\' to escape the next character.
+func Match(pattern, key string) bool {
+	return match([]byte(pattern), []byte(key))
+}
+
+func match(pattern, key []byte) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if match(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			key = key[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			pattern = pattern[1:]
+			not := len(pattern) > 0 && pattern[0] == '^'
+			if not {
+				pattern = pattern[1:]
+			}
+			matched := false
+			for len(pattern) > 0 && pattern[0] != ']' {
+				switch {
+				case pattern[0] == '\\' && len(pattern) >= 2:
+					pattern = pattern[1:]
+					if pattern[0] == key[0] {
+						matched = true
+					}
+				case len(pattern) >= 3 && pattern[1] == '-':
+					start, end := pattern[0], pattern[2]
+					if start > end {
+						start, end = end, start
+					}
+					if key[0] >= start && key[0] <= end {
+						matched = true
+					}
+					pattern = pattern[2:]
+				default:
+					if pattern[0] == key[0] {
+						matched = true
+					}
+				}
+				pattern = pattern[1:]
+			}
+			if not {
+				matched = !matched
+			}
+			if !matched {
+				return false
+			}
+			key = key[1:]
+			if len(pattern) == 0 {
+				// - unterminated class, redis treats the end of pattern as ']'
+				return len(key) == 0
+			}
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || pattern[0] != key[0] {
+				return false
+			}
+			key = key[1:]
+		}
+		pattern = pattern[1:]
+	}
+
+	return len(key) == 0
+}
@@ -0,0 +1,271 @@
+package pool
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/logs"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultClient              = "default"
+	DefaultHealthCheckInterval = 10 * time.Second
+)
+
+type (
+	// Factory creates a connected cache client, it is called again to reconnect
+	// a client that failed its health check.
+	Factory func() (cache.Cache, error)
+
+	Option struct {
+		Clients map[string]Factory
+		// Default names the client returned by Client and used by Use, it can be
+		// omitted when there is a single client or one is named DefaultClient.
+		Default             string
+		HealthCheckInterval time.Duration
+		Log                 logs.Logger
+	}
+
+	Pool interface {
+		cache.Pool
+		Get(name string) (cache.Cache, error)
+		UseNamed(name string, callback cache.PoolCallback) error
+		Healthy(name string) bool
+	}
+
+	member struct {
+		factory Factory
+		client  cache.Cache
+		healthy bool
+	}
+
+	pool struct {
+		option  *Option
+		mu      sync.RWMutex
+		members map[string]*member
+		// retired holds the replaced clients until the next health check, so the
+		// callers that got them before the swap can finish using them.
+		retired   map[string]cache.Cache
+		done      chan struct{}
+		wg        sync.WaitGroup
+		closeOnce sync.Once
+		closeErr  error
+	}
+)
+
+func getOption(option *Option) error {
+	if len(option.Clients) == 0 {
+		return errors.New("at least 1 client is required")
+	}
+
+	if option.Default == "" {
+		if _, ok := option.Clients[DefaultClient]; ok {
+			option.Default = DefaultClient
+		} else if len(option.Clients) == 1 {
+			for name := range option.Clients {
+				option.Default = name
+			}
+		} else {
+			return errors.New("default client is required")
+		}
+	}
+
+	if _, ok := option.Clients[option.Default]; !ok {
+		return errors.Errorf("default client %s is not registered", option.Default)
+	}
+
+	if option.HealthCheckInterval == 0 {
+		option.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+
+	if option.HealthCheckInterval < 0 {
+		return errors.New("health check interval must be positive")
+	}
+
+	if option.Log == nil {
+		logger, _ := logs.DefaultLog()
+		option.Log = logger
+	}
+
+	return nil
+}
+
+// New connects every client of the option and starts the health checks,
+// clients that fail a Ping are recreated from their factory.
+func New(option *Option) (Pool, error) {
+	if err := getOption(option); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	p := &pool{
+		option:  option,
+		members: make(map[string]*member),
+		retired: make(map[string]cache.Cache),
+		done:    make(chan struct{}),
+	}
+
+	for name, factory := range option.Clients {
+		client, err := factory()
+		if err != nil {
+			_ = p.closeClients()
+			return nil, errors.Wrapf(err, "failed to create cache client %s", name)
+		}
+		p.members[name] = &member{factory: factory, client: client, healthy: true}
+	}
+
+	p.wg.Add(1)
+	go p.healthCheck()
+
+	return p, nil
+}
+
+func (p *pool) Use(callback cache.PoolCallback) {
+	callback(p.Client())
+}
+
+func (p *pool) UseNamed(name string, callback cache.PoolCallback) error {
+	client, err := p.Get(name)
+	if err != nil {
+		return err
+	}
+
+	callback(client)
+	return nil
+}
+
+// Client returns the default client, callers should not keep it around since
+// it is replaced when the pool reconnects and closed one health check later.
+func (p *pool) Client() cache.Cache {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.members[p.option.Default].client
+}
+
+func (p *pool) Get(name string) (cache.Cache, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	m, ok := p.members[name]
+	if !ok {
+		return nil, errors.Errorf("cache client %s is not registered", name)
+	}
+
+	return m.client, nil
+}
+
+func (p *pool) Healthy(name string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	m, ok := p.members[name]
+	return ok && m.healthy
+}
+
+func (p *pool) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+
+		p.closeRetired()
+		p.closeErr = p.closeClients()
+	})
+
+	return p.closeErr
+}
+
+func (p *pool) closeClients() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	for name, m := range p.members {
+		if e := m.client.Close(); e != nil {
+			err = errors.Wrapf(e, "failed to close cache client %s", name)
+			p.option.Log.Error(err)
+		}
+	}
+
+	return err
+}
+
+func (p *pool) closeRetired() {
+	p.mu.Lock()
+	retired := p.retired
+	p.retired = make(map[string]cache.Cache)
+	p.mu.Unlock()
+
+	for name, client := range retired {
+		if err := client.Close(); err != nil {
+			p.option.Log.Errorf("failed to close stale cache client %s: %s", name, err.Error())
+		}
+	}
+}
+
+func (p *pool) healthCheck() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.option.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.check()
+		}
+	}
+}
+
+func (p *pool) check() {
+	p.closeRetired()
+
+	p.mu.RLock()
+	names := make([]string, 0, len(p.members))
+	for name := range p.members {
+		names = append(names, name)
+	}
+	p.mu.RUnlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		p.mu.RLock()
+		m := p.members[name]
+		client, factory := m.client, m.factory
+		p.mu.RUnlock()
+
+		err := client.Ping()
+		if err == nil {
+			p.setHealthy(m, true)
+			continue
+		}
+
+		p.option.Log.Errorf("cache client %s failed health check: %s", name, err.Error())
+		p.setHealthy(m, false)
+
+		replacement, err := factory()
+		if err != nil {
+			p.option.Log.Errorf("failed to reconnect cache client %s: %s", name, err.Error())
+			continue
+		}
+
+		p.mu.Lock()
+		m.client = replacement
+		m.healthy = true
+		p.retired[name] = client
+		p.mu.Unlock()
+
+		p.option.Log.Infof("cache client %s reconnected", name)
+	}
+}
+
+func (p *pool) setHealthy(m *member, healthy bool) {
+	p.mu.Lock()
+	m.healthy = healthy
+	p.mu.Unlock()
+}
@@ -0,0 +1,110 @@
+package pool
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/memory"
+)
+
+type brokenCache struct {
+	cache.Cache
+}
+
+func (c *brokenCache) Ping() error {
+	return errors.New("connection refused")
+}
+
+func Test_New_requires_default_for_many_clients(t *testing.T) {
+	_, err := New(&Option{Clients: map[string]Factory{
+		"session": func() (cache.Cache, error) { return memory.New(), nil },
+		"catalog": func() (cache.Cache, error) { return memory.New(), nil },
+	}})
+
+	if err == nil {
+		t.Error("should error without default client")
+	}
+}
+
+func Test_Get_returns_named_client(t *testing.T) {
+	session := memory.New()
+	p, err := New(&Option{
+		Default: "session",
+		Clients: map[string]Factory{
+			"session": func() (cache.Cache, error) { return session, nil },
+			"catalog": func() (cache.Cache, error) { return memory.New(), nil },
+		},
+	})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+	defer p.Close()
+
+	if p.Client() != session {
+		t.Error("expected default client to be session")
+	}
+
+	if _, err := p.Get("unknown"); err == nil {
+		t.Error("should error on unknown client")
+	}
+}
+
+func Test_health_check_reconnects_failed_client(t *testing.T) {
+	var created int32
+	p, err := New(&Option{
+		HealthCheckInterval: 10 * time.Millisecond,
+		Clients: map[string]Factory{
+			"default": func() (cache.Cache, error) {
+				if atomic.AddInt32(&created, 1) == 1 {
+					return &brokenCache{Cache: memory.New()}, nil
+				}
+				return memory.New(), nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+	defer p.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	if _, ok := p.Client().(*brokenCache); ok {
+		t.Error("broken client should be replaced")
+	}
+
+	if !p.Healthy("default") {
+		t.Error("reconnected client should be healthy")
+	}
+}
+
+func Test_New_rejects_negative_health_check_interval(t *testing.T) {
+	_, err := New(&Option{
+		HealthCheckInterval: -time.Second,
+		Clients:             map[string]Factory{"default": func() (cache.Cache, error) { return memory.New(), nil }},
+	})
+
+	if err == nil {
+		t.Error("should error on negative health check interval")
+	}
+}
+
+func Test_Close_twice(t *testing.T) {
+	p, err := New(&Option{Clients: map[string]Factory{
+		"default": func() (cache.Cache, error) { return memory.New(), nil },
+	}})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Error("should not error ", err)
+	}
+}
@@ -0,0 +1,134 @@
+package pubsub
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/logs"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PingInterval is how long a subscription stays idle before its connection is checked.
+	PingInterval = 30 * time.Second
+	// MaxBackoff caps the wait between two failed receives.
+	MaxBackoff = 5 * time.Second
+
+	bufferSize = 100
+)
+
+type (
+	subscription struct {
+		pubsub   *redis.PubSub
+		log      logs.Logger
+		messages chan *cache.Message
+		done     chan struct{}
+		once     sync.Once
+	}
+)
+
+// New waits for the subscription of ps to be confirmed and starts delivering its
+// messages. go-redis reconnects and subscribes again after a network error,
+// the errors are reported to log while it retries.
+func New(ps *redis.PubSub, log logs.Logger) (cache.Subscription, error) {
+	if _, err := ps.Receive(); err != nil {
+		_ = ps.Close()
+		return nil, errors.Wrap(err, "failed to subscribe")
+	}
+
+	s := &subscription{
+		pubsub:   ps,
+		log:      log,
+		messages: make(chan *cache.Message, bufferSize),
+		done:     make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s, nil
+}
+
+func (s *subscription) Messages() <-chan *cache.Message {
+	return s.messages
+}
+
+func (s *subscription) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		err = s.pubsub.Close()
+	})
+
+	return errors.WithStack(err)
+}
+
+func (s *subscription) run() {
+	defer close(s.messages)
+
+	var failures int
+	for {
+		msg, err := s.pubsub.ReceiveTimeout(PingInterval)
+		if s.closed() {
+			return
+		}
+
+		if err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				if err := s.pubsub.Ping(); err != nil {
+					s.log.Errorf("failed to ping subscription: %s", err.Error())
+				}
+				continue
+			}
+
+			failures++
+			s.log.Errorf("failed to receive subscription message: %s", err.Error())
+			if !s.wait(backoff(failures)) {
+				return
+			}
+			continue
+		}
+
+		failures = 0
+		if m, ok := msg.(*redis.Message); ok {
+			select {
+			case s.messages <- &cache.Message{Channel: m.Channel, Pattern: m.Pattern, Payload: m.Payload}:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+func (s *subscription) closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *subscription) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func backoff(failures int) time.Duration {
+	d := 100 * time.Millisecond << uint(failures-1)
+	if d <= 0 || d > MaxBackoff {
+		return MaxBackoff
+	}
+
+	return d
+}
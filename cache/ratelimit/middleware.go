@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/jajotz/utilities-golang/http"
+	"github.com/jajotz/utilities-golang/shared/dto"
+	"github.com/jajotz/utilities-golang/util/mwerror"
+
+	"github.com/pkg/errors"
+)
+
+type KeyFunc func(http.RequestContext) string
+
+// ByIP limits each client address.
+func ByIP(c http.RequestContext) string {
+	return "ip:" + c.RealIP()
+}
+
+// ByHeader limits each distinct value of the header name, e.g. a user id or an API key.
+func ByHeader(name string) KeyFunc {
+	return func(c http.RequestContext) string {
+		return "header:" + name + ":" + c.Request().Header.Get(name)
+	}
+}
+
+// Middleware rejects requests above the limit with the TOO_MANY_REQUEST error
+// standard, it is registered with http.Server Use or as a route handler.
+// Requests are let through when the limiter itself fails.
+func Middleware(limiter Limiter, key KeyFunc) http.HandlerFunc {
+	return func(c http.RequestContext) error {
+		result, err := limiter.AllowWithContext(c.Request().Context(), key(c))
+		if err != nil {
+			c.Logger().Error(errors.Wrap(err, "failed to check rate limit"))
+			return nil
+		}
+
+		header := c.Response().Header()
+		header.Set("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+		header.Set("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(int64(result.ResetAfter/time.Second), 10))
+
+		if result.Allowed {
+			return nil
+		}
+
+		header.Set("Retry-After", strconv.FormatInt(int64((result.RetryAfter+time.Second-1)/time.Second), 10))
+
+		mwErr := mwerror.New(mwerror.TOO_MANY_REQUEST, errors.New("rate limit exceeded"))
+		if err := c.JSON(mwErr.GetHTTPStatus(), &dto.BaseResponseDto{
+			Code:       mwErr.GetCode(),
+			Message:    mwErr.GetMessage(),
+			Errors:     mwErr.GetErrors(),
+			ServerTime: time.Now().Unix(),
+		}); err != nil {
+			return err
+		}
+
+		return mwErr
+	}
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/http"
+	"github.com/jajotz/utilities-golang/shared/dto"
+	"github.com/jajotz/utilities-golang/util/mwerror"
+)
+
+func Test_Middleware_rejects_requests_above_limit(t *testing.T) {
+	limiter, server := newLimiter(t, &Option{Algorithm: FixedWindow, Limit: 1, Window: time.Minute})
+	defer server.Close()
+
+	s := http.New()
+	s.GET("/orders", func(c http.RequestContext) error {
+		return c.String(nethttp.StatusOK, "ok")
+	}, Middleware(limiter, ByHeader("X-User")))
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(nethttp.MethodGet, "/orders", nil)
+		req.Header.Set("X-User", "42")
+		res := httptest.NewRecorder()
+		s.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve()
+	if res.Code != nethttp.StatusOK || res.Body.String() != "ok" {
+		t.Fatalf("first request should be allowed, got %d %s", res.Code, res.Body.String())
+	}
+
+	if res.Header().Get("X-RateLimit-Limit") != "1" || res.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected quota headers %+v", res.Header())
+	}
+
+	res = serve()
+	if res.Code != nethttp.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d %s", res.Code, res.Body.String())
+	}
+
+	if res.Header().Get("X-RateLimit-Remaining") != "0" || res.Header().Get("X-RateLimit-Reset") == "" || res.Header().Get("Retry-After") != "60" {
+		t.Errorf("unexpected rate limit headers %+v", res.Header())
+	}
+
+	body := dto.BaseResponseDto{}
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if body.Code != mwerror.TOO_MANY_REQUEST || body.Message != "Too many request" || len(body.Errors) != 1 {
+		t.Errorf("unexpected body %+v", body)
+	}
+}
@@ -0,0 +1,284 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+type Algorithm string
+
+const (
+	FixedWindow      Algorithm = "FIXED_WINDOW"
+	SlidingWindowLog Algorithm = "SLIDING_WINDOW_LOG"
+	TokenBucket      Algorithm = "TOKEN_BUCKET"
+
+	DefaultPrefix = "ratelimit:"
+)
+
+var (
+	fixedWindow = redis.NewScript(`
+local current = redis.call("INCRBY", KEYS[1], ARGV[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	ttl = tonumber(ARGV[2])
+end
+return {current, ttl}`)
+
+	slidingWindowLog = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+if count + n <= limit then
+	for i = 1, n do
+		redis.call("ZADD", KEYS[1], now, ARGV[5] .. ":" .. i)
+	end
+	count = count + n
+	allowed = 1
+end
+redis.call("PEXPIRE", KEYS[1], window)
+local reset = 0
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}`)
+
+	tokenBucket = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= n then
+	tokens = tokens - n
+	allowed = 1
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(capacity / rate))
+return {allowed, tostring(tokens)}`)
+)
+
+type (
+	Option struct {
+		Algorithm Algorithm
+		// Limit is the number of requests allowed per Window, it is the bucket
+		// capacity for TokenBucket which refills Limit tokens every Window.
+		Limit  int64
+		Window time.Duration
+		Prefix string
+	}
+
+	Result struct {
+		Allowed   bool
+		Limit     int64
+		Remaining int64
+		// ResetAfter is the time until the quota is fully available again.
+		ResetAfter time.Duration
+		// RetryAfter is the time to wait before the next request can be allowed,
+		// it is zero when the request was allowed.
+		RetryAfter time.Duration
+	}
+
+	Limiter interface {
+		AllowWithContext(ctx context.Context, key string) (*Result, error)
+		Allow(key string) (*Result, error)
+		AllowNWithContext(ctx context.Context, key string, n int64) (*Result, error)
+		AllowN(key string, n int64) (*Result, error)
+	}
+
+	limiter struct {
+		option *Option
+		client redis.UniversalClient
+	}
+)
+
+func getOption(option *Option) error {
+	if option.Limit <= 0 {
+		return errors.New("limit must be greater than zero")
+	}
+
+	if option.Window <= 0 {
+		return errors.New("window must be greater than zero")
+	}
+
+	if option.Algorithm == "" {
+		option.Algorithm = FixedWindow
+	}
+
+	if option.Algorithm != FixedWindow && option.Algorithm != SlidingWindowLog && option.Algorithm != TokenBucket {
+		return errors.New("invalid rate limit algorithm")
+	}
+
+	if option.Prefix == "" {
+		option.Prefix = DefaultPrefix
+	}
+
+	return nil
+}
+
+// New creates a Limiter on top of one of the redis backed caches.
+func New(client cache.Cache, option *Option) (Limiter, error) {
+	instance, ok := client.(cache.Instance)
+	if !ok {
+		return nil, errors.New("cache client is not backed by redis")
+	}
+
+	if err := getOption(option); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &limiter{option: option, client: instance.Instance()}, nil
+}
+
+func (l *limiter) AllowWithContext(ctx context.Context, key string) (*Result, error) {
+	return l.AllowNWithContext(ctx, key, 1)
+}
+
+func (l *limiter) Allow(key string) (*Result, error) {
+	return l.AllowNWithContext(context.Background(), key, 1)
+}
+
+func (l *limiter) AllowNWithContext(ctx context.Context, key string, n int64) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	key = l.option.Prefix + key
+
+	switch l.option.Algorithm {
+	case SlidingWindowLog:
+		return l.slidingWindowLog(key, n)
+	case TokenBucket:
+		return l.tokenBucket(key, n)
+	default:
+		return l.fixedWindow(key, n)
+	}
+}
+
+func (l *limiter) AllowN(key string, n int64) (*Result, error) {
+	return l.AllowNWithContext(context.Background(), key, n)
+}
+
+func (l *limiter) fixedWindow(key string, n int64) (*Result, error) {
+	window := milliseconds(l.option.Window)
+	res, err := fixedWindow.Run(l.client, []string{key}, n, window).Result()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run fixed window rate limit on key %s", key)
+	}
+
+	values := res.([]interface{})
+	current, ttl := values[0].(int64), values[1].(int64)
+
+	result := &Result{
+		Allowed:    current <= l.option.Limit,
+		Limit:      l.option.Limit,
+		Remaining:  remaining(l.option.Limit, current),
+		ResetAfter: time.Duration(ttl) * time.Millisecond,
+	}
+
+	if !result.Allowed {
+		result.RetryAfter = result.ResetAfter
+	}
+
+	return result, nil
+}
+
+func (l *limiter) slidingWindowLog(key string, n int64) (*Result, error) {
+	member, err := newMember()
+	if err != nil {
+		return nil, err
+	}
+
+	now := milliseconds(time.Duration(time.Now().UnixNano()))
+	window := milliseconds(l.option.Window)
+	res, err := slidingWindowLog.Run(l.client, []string{key}, now, window, l.option.Limit, n, member).Result()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run sliding window rate limit on key %s", key)
+	}
+
+	values := res.([]interface{})
+	allowed, count, reset := values[0].(int64), values[1].(int64), values[2].(int64)
+
+	result := &Result{
+		Allowed:    allowed == 1,
+		Limit:      l.option.Limit,
+		Remaining:  remaining(l.option.Limit, count),
+		ResetAfter: time.Duration(reset) * time.Millisecond,
+	}
+
+	if !result.Allowed {
+		result.RetryAfter = result.ResetAfter
+	}
+
+	return result, nil
+}
+
+func (l *limiter) tokenBucket(key string, n int64) (*Result, error) {
+	now := milliseconds(time.Duration(time.Now().UnixNano()))
+	rate := float64(l.option.Limit) / float64(milliseconds(l.option.Window))
+	res, err := tokenBucket.Run(l.client, []string{key}, l.option.Limit, strconv.FormatFloat(rate, 'f', -1, 64), now, n).Result()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run token bucket rate limit on key %s", key)
+	}
+
+	values := res.([]interface{})
+	tokens, err := strconv.ParseFloat(values[1].(string), 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid token bucket state on key %s", key)
+	}
+
+	result := &Result{
+		Allowed:    values[0].(int64) == 1,
+		Limit:      l.option.Limit,
+		Remaining:  int64(tokens),
+		ResetAfter: time.Duration((float64(l.option.Limit)-tokens)/rate) * time.Millisecond,
+	}
+
+	if !result.Allowed {
+		result.RetryAfter = time.Duration((float64(n)-tokens)/rate) * time.Millisecond
+	}
+
+	return result, nil
+}
+
+func remaining(limit, used int64) int64 {
+	if used >= limit {
+		return 0
+	}
+
+	return limit - used
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+func newMember() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate rate limit member")
+	}
+
+	return strconv.FormatInt(time.Now().UnixNano(), 10) + "-" + hex.EncodeToString(b), nil
+}
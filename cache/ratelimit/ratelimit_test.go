@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache/memory"
+	"github.com/jajotz/utilities-golang/cache/redis"
+	"github.com/jajotz/utilities-golang/cache/redistest"
+)
+
+func newLimiter(t *testing.T, option *Option) (Limiter, *redistest.Server) {
+	server := redistest.Run(t)
+
+	client, err := redis.New(&redis.Option{Address: server.Addr()})
+	if err != nil {
+		server.Close()
+		t.Fatal("should not error ", err)
+	}
+
+	limiter, err := New(client, option)
+	if err != nil {
+		server.Close()
+		t.Fatal("should not error ", err)
+	}
+
+	return limiter, server
+}
+
+func Test_New_requires_redis(t *testing.T) {
+	if _, err := New(memory.New(), &Option{Limit: 1, Window: time.Second}); err == nil {
+		t.Error("should error on a cache not backed by redis")
+	}
+}
+
+func Test_New_rejects_invalid_option(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	client, err := redis.New(&redis.Option{Address: server.Addr()})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	for _, option := range []*Option{
+		{Limit: 0, Window: time.Second},
+		{Limit: 1, Window: 0},
+		{Limit: 1, Window: time.Second, Algorithm: "LEAKY_BUCKET"},
+	} {
+		if _, err := New(client, option); err == nil {
+			t.Errorf("should error on option %+v", option)
+		}
+	}
+}
+
+func Test_FixedWindow(t *testing.T) {
+	limiter, server := newLimiter(t, &Option{Algorithm: FixedWindow, Limit: 2, Window: time.Minute})
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Allow("client")
+		if err != nil {
+			t.Fatal("should not error ", err)
+		}
+
+		if !result.Allowed || result.Remaining != int64(1-i) {
+			t.Errorf("request %d should be allowed, got %+v", i, result)
+		}
+	}
+
+	result, err := limiter.Allow("client")
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter <= 0 || result.RetryAfter > time.Minute {
+		t.Errorf("request above the limit should be rejected, got %+v", result)
+	}
+
+	if result, _ := limiter.Allow("other"); !result.Allowed {
+		t.Error("keys should be limited separately")
+	}
+
+	server.FastForward(time.Minute)
+
+	if result, _ := limiter.Allow("client"); !result.Allowed {
+		t.Error("request should be allowed in the next window")
+	}
+}
+
+func Test_SlidingWindowLog(t *testing.T) {
+	limiter, server := newLimiter(t, &Option{Algorithm: SlidingWindowLog, Limit: 3, Window: time.Minute})
+	defer server.Close()
+
+	result, err := limiter.AllowN("client", 2)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if !result.Allowed || result.Remaining != 1 {
+		t.Errorf("requests should be allowed, got %+v", result)
+	}
+
+	result, err = limiter.AllowN("client", 2)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if result.Allowed || result.Remaining != 1 || result.RetryAfter <= 0 || result.RetryAfter > time.Minute {
+		t.Errorf("requests above the limit should be rejected, got %+v", result)
+	}
+
+	// - a rejected request is not recorded
+	if result, _ := limiter.Allow("client"); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("request within the limit should be allowed, got %+v", result)
+	}
+}
+
+func Test_TokenBucket(t *testing.T) {
+	limiter, server := newLimiter(t, &Option{Algorithm: TokenBucket, Limit: 2, Window: time.Hour})
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		if result, err := limiter.Allow("client"); err != nil || !result.Allowed {
+			t.Fatalf("request %d should be allowed, got %+v %v", i, result, err)
+		}
+	}
+
+	result, err := limiter.Allow("client")
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	// - the bucket refills a token every 30 minutes
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter <= 29*time.Minute || result.RetryAfter > 30*time.Minute {
+		t.Errorf("request on an empty bucket should be rejected, got %+v", result)
+	}
+
+	if result.ResetAfter <= 59*time.Minute || result.ResetAfter > time.Hour {
+		t.Errorf("unexpected reset %s", result.ResetAfter)
+	}
+}
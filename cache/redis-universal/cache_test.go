@@ -0,0 +1,17 @@
+package redis_universal
+
+import (
+	"testing"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/redistest"
+)
+
+func Test_conformance(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	server.Conformance(t, func() (cache.Cache, error) {
+		return New(&Option{Address: []string{server.Addr()}})
+	})
+}
@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/redistest"
+	"github.com/jajotz/utilities-golang/config"
+
+	miniserver "github.com/alicebob/miniredis/v2/server"
+	"github.com/pkg/errors"
+)
+
+func Test_Option_loads_from_config(t *testing.T) {
+	env := map[string]string{
+		"REDIS_USERNAME":        "app",
+		"REDIS_TLS":             "true",
+		"REDIS_TLSCAFILE":       "/etc/redis/ca.pem",
+		"REDIS_MASTERNAME":      "mymaster",
+		"REDIS_SENTINELADDRS":   "10.0.0.1:26379,10.0.0.2:26379",
+		"REDIS_MAXRETRIES":      "3",
+		"REDIS_MINRETRYBACKOFF": "10ms",
+		"REDIS_MAXRETRYBACKOFF": "1s",
+	}
+	for key, value := range env {
+		_ = os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+
+	object := struct{ Redis Option }{}
+	if err := config.NewFromEnv(&object); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	option := object.Redis
+	if option.Username != "app" || !option.TLS || option.TLSCAFile != "/etc/redis/ca.pem" || option.MasterName != "mymaster" {
+		t.Errorf("unexpected option %+v", option)
+	}
+
+	if len(option.SentinelAddrs) != 2 || option.SentinelAddrs[1] != "10.0.0.2:26379" {
+		t.Errorf("unexpected sentinel addresses %+v", option.SentinelAddrs)
+	}
+
+	if option.MaxRetries != 3 || option.MinRetryBackoff != 10*time.Millisecond || option.MaxRetryBackoff != time.Second {
+		t.Errorf("unexpected retry option %+v", option)
+	}
+}
+
+func Test_conformance(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	server.Conformance(t, func() (cache.Cache, error) {
+		return New(&Option{Address: server.Addr()})
+	})
+}
+
+func Test_UnlinkByPattern_falls_back_to_del(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	// - redis before 4.0 doesn't know UNLINK
+	server.AddPreHook(func(c *miniserver.Peer, cmd string, args ...string) bool {
+		if strings.ToUpper(cmd) != "UNLINK" {
+			return false
+		}
+
+		c.WriteError("ERR unknown command 'UNLINK'")
+		return true
+	})
+
+	c, err := New(&Option{Address: server.Addr()})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+	defer c.Close()
+
+	_ = c.Set("user:1", "a")
+	_ = c.Set("user:2", "b")
+	_ = c.Set("other", "c")
+
+	removed, err := c.(cache.Scanner).UnlinkByPattern("user:*", 10)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if removed != 2 || server.Exists("user:1") || server.Exists("user:2") || !server.Exists("other") {
+		t.Errorf("unexpected removal %d %+v", removed, server.Keys())
+	}
+}
+
+func Test_PipelineWithContext_reports_cancelled_context(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	c, err := New(&Option{Address: server.Addr()})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, p := range []cache.Pipe{c.PipelineWithContext(ctx), c.TxPipelineWithContext(ctx)} {
+		_ = p.Set("key", "value")
+		incr := p.Incr("counter")
+
+		if err := p.Exec(); errors.Cause(err) != context.Canceled {
+			t.Errorf("expected canceled pipe, got %v", err)
+		}
+
+		if errors.Cause(incr.Err()) != context.Canceled {
+			t.Errorf("expected canceled command, got %v", incr.Err())
+		}
+	}
+
+	if server.Exists("key") || server.Exists("counter") {
+		t.Error("cancelled pipe should not reach the server")
+	}
+}
+
+func Test_ScanKeysWithContext_accepts_nil_context(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	c, err := New(&Option{Address: server.Addr()})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+	defer c.Close()
+
+	_ = c.Set("key", "value")
+
+	keys, err := c.(cache.Scanner).ScanKeysWithContext(nil, "*", 10)
+	if err != nil || len(keys) != 1 {
+		t.Errorf("unexpected keys %+v %v", keys, err)
+	}
+}
@@ -0,0 +1,659 @@
+package rediscore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/codec"
+	"github.com/jajotz/utilities-golang/cache/instrument"
+	"github.com/jajotz/utilities-golang/cache/pubsub"
+	"github.com/jajotz/utilities-golang/logs"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+// withExpiration writes KEYS[1] with the command of ARGV[3] and applies the ttl
+// of ARGV[1] in milliseconds, the key is removed first when ARGV[2] is set.
+// The pairs of arguments are sent in batches to stay below the lua stack limit.
+var withExpiration = redis.NewScript(`
+if ARGV[2] == "1" then
+	redis.call("DEL", KEYS[1])
+end
+for i = 4, #ARGV, 1000 do
+	redis.call(ARGV[3], KEYS[1], unpack(ARGV, i, math.min(i + 999, #ARGV)))
+end
+if tonumber(ARGV[1]) >= 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return 1`)
+
+type (
+	// Option holds the settings shared by the redis caches, the connection
+	// settings are mapped by the redis, redis-cluster and redis-universal packages.
+	Option struct {
+		// Codec serializes cached values, defaults to codec.Binary.
+		Codec codec.Codec
+		// Compression compresses the values larger than CompressionThreshold bytes,
+		// one of codec.Gzip, codec.Snappy or codec.Zstd.
+		Compression          codec.Compression
+		CompressionThreshold int
+		// EncryptionKeys enables AES-GCM encryption of the values with the key of
+		// EncryptionKeyID, the other keys are only used to read older values.
+		EncryptionKeys  map[string][]byte
+		EncryptionKeyID string
+		// Log reports the errors of background work such as subscriptions.
+		Log logs.Logger
+		// Hooks observe every command, see the instrument package for metrics,
+		// tracing and slow log hooks.
+		Hooks []cache.Hook
+	}
+
+	client struct {
+		r     redis.UniversalClient
+		codec codec.Codec
+		log   logs.Logger
+		hooks []cache.Hook
+	}
+)
+
+// New builds the cache on top of a go-redis client, either a *redis.Client or
+// a *redis.ClusterClient, the client is closed when it can't be used.
+func New(r redis.UniversalClient, option *Option) (cache.Cache, error) {
+	if option.Codec == nil {
+		option.Codec = codec.Binary
+	}
+
+	cdc, err := codec.Transform(option.Codec, &codec.TransformOption{
+		Compression:          option.Compression,
+		CompressionThreshold: option.CompressionThreshold,
+		EncryptionKeys:       option.EncryptionKeys,
+		EncryptionKeyID:      option.EncryptionKeyID,
+	})
+	if err != nil {
+		_ = r.Close()
+		return nil, errors.Wrap(err, "invalid codec option")
+	}
+
+	if _, err := r.Ping().Result(); err != nil {
+		_ = r.Close()
+		return nil, errors.Wrap(err, "Failed to connect to redis!")
+	}
+
+	if option.Log == nil {
+		logger, _ := logs.DefaultLog()
+		option.Log = logger
+	}
+
+	return &client{r: r, codec: cdc, log: option.Log, hooks: option.Hooks}, nil
+}
+
+func (c *client) PingWithContext(ctx context.Context) error {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.Ping().Result(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (c *client) Ping() error {
+	return c.PingWithContext(context.Background())
+}
+
+func (c *client) SetWithExpirationWithContext(ctx context.Context, key string, value interface{}, duration time.Duration) error {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	val, err := c.codec.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set cache with key %s!", key)
+	}
+
+	if _, err := r.Set(key, val, duration).Result(); err != nil {
+		return errors.Wrapf(err, "failed to set cache with key %s!", key)
+	}
+	return nil
+}
+
+func (c *client) SetWithExpiration(key string, value interface{}, duration time.Duration) error {
+	return c.SetWithExpirationWithContext(context.Background(), key, value, duration)
+}
+
+func (c *client) SetWithContext(ctx context.Context, key string, value interface{}) error {
+	return c.SetWithExpirationWithContext(ctx, key, value, 0)
+}
+
+func (c *client) Set(key string, value interface{}) error {
+	return c.SetWithContext(context.Background(), key, value)
+}
+
+func (c *client) GetWithContext(ctx context.Context, key string, data interface{}) error {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	val, err := r.Get(key).Bytes()
+
+	if err == redis.Nil {
+		return errors.Wrapf(err, "key %s does not exits", key)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if err := c.codec.Unmarshal(val, data); err != nil {
+		return errors.Wrapf(err, "failed to get cache with key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *client) Get(key string, data interface{}) error {
+	return c.GetWithContext(context.Background(), key, data)
+}
+
+func (c *client) KeysWithContext(ctx context.Context, pattern string) ([]string, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return []string{}, err
+	}
+
+	var mu sync.Mutex
+	keys := make([]string, 0)
+	err = c.forEachNode(ctx, r, func(node *redis.Client) error {
+		val, err := node.Keys(pattern).Result()
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		keys = append(keys, val...)
+		mu.Unlock()
+		return nil
+	})
+
+	return keys, errors.WithStack(err)
+}
+
+func (c *client) Keys(pattern string) ([]string, error) {
+	return c.KeysWithContext(context.Background(), pattern)
+}
+
+func (c *client) RemoveWithContext(ctx context.Context, key string) error {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.Del(key).Result(); err != nil {
+		return errors.Wrapf(err, "failed to remove key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *client) Remove(key string) error {
+	return c.RemoveWithContext(context.Background(), key)
+}
+
+func (c *client) RemoveByPatternWithContext(ctx context.Context, pattern string, countPerLoop int64) error {
+	_, err := c.UnlinkByPatternWithContext(ctx, pattern, countPerLoop)
+	return err
+}
+
+func (c *client) RemoveByPattern(pattern string, countPerLoop int64) error {
+	return c.RemoveByPatternWithContext(context.Background(), pattern, countPerLoop)
+}
+
+func (c *client) FlushDatabaseWithContext(ctx context.Context) error {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.FlushDB().Result(); err != nil {
+		return errors.Wrap(err, "failed to flush db!")
+	}
+
+	return nil
+}
+
+func (c *client) FlushDatabase() error {
+	return c.FlushDatabaseWithContext(context.Background())
+}
+
+func (c *client) FlushAllWithContext(ctx context.Context) error {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.FlushAll().Result(); err != nil {
+		return errors.Wrap(err, "failed to flush db!")
+	}
+
+	return nil
+}
+
+func (c *client) FlushAll() error {
+	return c.FlushAllWithContext(context.Background())
+}
+
+func (c *client) Close() error {
+	if err := c.r.Close(); err != nil {
+		return errors.Wrap(err, "failed to close redis client")
+	}
+
+	return nil
+}
+
+func check(c *client) error {
+	if c.r == nil {
+		return errors.New("redis client is not connected")
+	}
+
+	return nil
+}
+
+// withContext binds the client to ctx after making sure it is still alive,
+// go-redis v6 does not abort commands already sent to the server.
+func (c *client) withContext(ctx context.Context) (redis.UniversalClient, error) {
+	if err := check(c); err != nil {
+		return nil, err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := alive(ctx); err != nil {
+		return nil, err
+	}
+
+	var r redis.UniversalClient
+	switch v := c.r.(type) {
+	case *redis.Client:
+		r = v.WithContext(ctx)
+	case *redis.ClusterClient:
+		r = v.WithContext(ctx)
+	default:
+		return c.r, nil
+	}
+
+	instrument.Wrap(ctx, r, c.hooks)
+	return r, nil
+}
+
+// alive returns the error of ctx once it is done, a nil ctx never is.
+func alive(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+
+	return errors.WithStack(ctx.Err())
+}
+
+func (c *client) SetZSetWithExpirationWithContext(ctx context.Context, key string, duration time.Duration, data ...redis.Z) error {
+	return c.setZSet(ctx, key, duration, data...)
+}
+
+func (c *client) SetZSetWithExpiration(key string, duration time.Duration, data ...redis.Z) error {
+	return c.SetZSetWithExpirationWithContext(context.Background(), key, duration, data...)
+}
+
+func (c *client) SetZSetWithContext(ctx context.Context, key string, data ...redis.Z) error {
+	return c.setZSet(ctx, key, -1, data...)
+}
+
+// setZSet replaces the sorted set with a script, it is left without expiration
+// when duration is negative.
+func (c *client) setZSet(ctx context.Context, key string, duration time.Duration, data ...redis.Z) error {
+	if len(data) == 0 {
+		return errors.Wrapf(errors.New("ERR wrong number of arguments for 'zadd' command"), "failed to zadd cache with key %s!", key)
+	}
+
+	args := make([]interface{}, 0, 2*len(data))
+	for _, z := range data {
+		args = append(args, z.Score, z.Member)
+	}
+
+	if err := c.writeWithExpiration(ctx, key, duration, true, "ZADD", args...); err != nil {
+		return errors.Wrapf(err, "failed to zadd cache with key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *client) SetZSet(key string, data ...redis.Z) error {
+	return c.SetZSetWithContext(context.Background(), key, data...)
+}
+
+func (c *client) GetZSetWithContext(ctx context.Context, key string) ([]redis.Z, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	data, err := r.ZRangeWithScores(key, 0, -1).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run zrange command")
+	}
+
+	if len(data) <= 0 {
+		return nil, errors.New(fmt.Sprintf("key %s does not exits", key))
+	}
+
+	return data, nil
+}
+
+func (c *client) GetZSet(key string) ([]redis.Z, error) {
+	return c.GetZSetWithContext(context.Background(), key)
+}
+
+func (c *client) HMSetWithExpirationWithContext(ctx context.Context, key string, value map[string]interface{}, ttl time.Duration) error {
+	if len(value) == 0 {
+		return errors.Wrapf(errors.New("ERR wrong number of arguments for 'hmset' command"), "failed to HMSet cache with key %s!", key)
+	}
+
+	fields, err := marshalFields(c.codec, value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
+	}
+
+	args := make([]interface{}, 0, 2*len(fields))
+	for field, val := range fields {
+		args = append(args, field, val)
+	}
+
+	if err := c.writeWithExpiration(ctx, key, ttl, false, "HMSET", args...); err != nil {
+		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *client) HMSetWithExpiration(key string, value map[string]interface{}, ttl time.Duration) error {
+	return c.HMSetWithExpirationWithContext(context.Background(), key, value, ttl)
+}
+
+func (c *client) HMSetWithContext(ctx context.Context, key string, value map[string]interface{}) error {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	fields, err := marshalFields(c.codec, value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
+	}
+
+	if _, err := r.HMSet(key, fields).Result(); err != nil {
+		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
+	}
+	return nil
+}
+
+func (c *client) HMSet(key string, value map[string]interface{}) error {
+	return c.HMSetWithContext(context.Background(), key, value)
+}
+
+func (c *client) HSetWithExpirationWithContext(ctx context.Context, key, field string, value interface{}, ttl time.Duration) error {
+	val, err := c.codec.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to HSet cache with key %s!", key)
+	}
+
+	if err := c.writeWithExpiration(ctx, key, ttl, false, "HMSET", field, val); err != nil {
+		return errors.Wrapf(err, "failed to HSet cache with key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *client) HSetWithExpiration(key, field string, value interface{}, ttl time.Duration) error {
+	return c.HSetWithExpirationWithContext(context.Background(), key, field, value, ttl)
+}
+
+func (c *client) HSetWithContext(ctx context.Context, key, field string, value interface{}) error {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	val, err := c.codec.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to HSet cache with key %s!", key)
+	}
+
+	if _, err := r.HSet(key, field, val).Result(); err != nil {
+		return errors.Wrapf(err, "failed to HSet cache with key %s!", key)
+	}
+	return nil
+}
+
+func (c *client) HSet(key, field string, value interface{}) error {
+	return c.HSetWithContext(context.Background(), key, field, value)
+}
+
+func (c *client) HMGetWithContext(ctx context.Context, key string, fields ...string) ([]interface{}, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := r.HMGet(key, fields...).Result()
+	if err == redis.Nil {
+		return nil, errors.Wrapf(err, "key %s does not exits", key)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return val, nil
+}
+
+func (c *client) HMGet(key string, fields ...string) ([]interface{}, error) {
+	return c.HMGetWithContext(context.Background(), key, fields...)
+}
+
+func (c *client) HGetAllWithContext(ctx context.Context, key string) (map[string]string, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := r.HGetAll(key).Result()
+	if err == redis.Nil {
+		return nil, errors.Wrapf(err, "key %s does not exits", key)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return val, nil
+}
+
+func (c *client) HGetAll(key string) (map[string]string, error) {
+	return c.HGetAllWithContext(context.Background(), key)
+}
+
+func (c *client) HGetWithContext(ctx context.Context, key, field string, response interface{}) error {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	val, err := r.HGet(key, field).Bytes()
+	if err == redis.Nil {
+		return errors.Wrapf(err, "key %s does not exits", key)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	if err := c.codec.Unmarshal(val, response); err != nil {
+		return errors.Wrapf(err, "failed to get cache with key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *client) HGet(key, field string, response interface{}) error {
+	return c.HGetWithContext(context.Background(), key, field, response)
+}
+
+func (c *client) MGetWithContext(ctx context.Context, key []string) ([]interface{}, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := r.MGet(key...).Result()
+	if err == redis.Nil {
+		return nil, errors.Wrapf(err, "key %s does not exits", key)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s!", key)
+	}
+
+	return val, nil
+}
+
+func (c *client) MGet(key []string) ([]interface{}, error) {
+	return c.MGetWithContext(context.Background(), key)
+}
+
+func (c *client) Client() cache.Cache {
+	return c
+}
+
+func (c *client) PipelineWithContext(ctx context.Context) cache.Pipe {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return &failedPipe{err: err}
+	}
+
+	return &pipe{instance: r.Pipeline(), codec: c.codec}
+}
+
+func (c *client) Pipeline() cache.Pipe {
+	return c.PipelineWithContext(context.Background())
+}
+
+// TxPipelineWithContext queues the commands inside MULTI/EXEC so they are applied atomically,
+// on a cluster the keys of a transaction must hash to the same slot.
+func (c *client) TxPipelineWithContext(ctx context.Context) cache.Pipe {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return &failedPipe{err: err}
+	}
+
+	return &pipe{instance: r.TxPipeline(), codec: c.codec}
+}
+
+func (c *client) TxPipeline() cache.Pipe {
+	return c.TxPipelineWithContext(context.Background())
+}
+
+func (c *client) PublishWithContext(ctx context.Context, channel string, message interface{}) error {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Publish(channel, message).Err(); err != nil {
+		return errors.Wrapf(err, "failed to publish to channel %s!", channel)
+	}
+	return nil
+}
+
+func (c *client) Publish(channel string, message interface{}) error {
+	return c.PublishWithContext(context.Background(), channel, message)
+}
+
+func (c *client) Subscribe(channels ...string) (cache.Subscription, error) {
+	subscription, err := pubsub.New(c.r.Subscribe(channels...), c.log)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to subscribe to channels %v!", channels)
+	}
+	return subscription, nil
+}
+
+func (c *client) PSubscribe(patterns ...string) (cache.Subscription, error) {
+	subscription, err := pubsub.New(c.r.PSubscribe(patterns...), c.log)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to subscribe to patterns %v!", patterns)
+	}
+	return subscription, nil
+}
+
+func (c *client) Instance() redis.UniversalClient {
+	if r, err := c.withContext(context.Background()); err == nil {
+		return r
+	}
+	return c.r
+}
+
+// Decode unmarshals a raw value returned by MGet with the configured codec.
+func (c *client) Decode(value interface{}, object interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return c.codec.Unmarshal([]byte(v), object)
+	case []byte:
+		return c.codec.Unmarshal(v, object)
+	case nil:
+		return redis.Nil
+	default:
+		return errors.New(fmt.Sprintf("can't decode value of type %T", value))
+	}
+}
+
+// writeWithExpiration runs command on key with args and applies ttl in a single
+// script, key is removed first when replace is set and a negative ttl leaves
+// it without expiration, a ttl under a millisecond is rounded up to one. The
+// args are field and value or score and member pairs.
+func (c *client) writeWithExpiration(ctx context.Context, key string, ttl time.Duration, replace bool, command string, args ...interface{}) error {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	ms := int64(-1)
+	switch {
+	case ttl > 0 && ttl < time.Millisecond:
+		ms = 1
+	case ttl >= 0:
+		ms = int64(ttl / time.Millisecond)
+	}
+
+	argv := append([]interface{}{ms, replace, command}, args...)
+	return withExpiration.Run(r, []string{key}, argv...).Err()
+}
+
+func marshalFields(cdc codec.Codec, value map[string]interface{}) (map[string]interface{}, error) {
+	fields := make(map[string]interface{}, len(value))
+	for field, v := range value {
+		val, err := cdc.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal field %s", field)
+		}
+		fields[field] = val
+	}
+
+	return fields, nil
+}
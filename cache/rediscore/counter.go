@@ -0,0 +1,163 @@
+package rediscore
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+// expireOnCreate runs the command of ARGV[2] on KEYS[1] and applies the ttl of
+// ARGV[1] in milliseconds when the command created the key. The arguments are
+// unpacked 1000 at a time to stay below the Lua stack limit, only PFADD takes
+// that many and its result tells whether any of the calls changed the estimate.
+var expireOnCreate = redis.NewScript(`
+local created = redis.call("EXISTS", KEYS[1]) == 0
+local result = redis.call(ARGV[2], KEYS[1], unpack(ARGV, 3, math.min(1002, #ARGV)))
+for i = 1003, #ARGV, 1000 do
+	if redis.call(ARGV[2], KEYS[1], unpack(ARGV, i, math.min(i + 999, #ARGV))) == 1 then
+		result = 1
+	end
+end
+if created and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return result`)
+
+func (c *client) IncrWithContext(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(ctx, key, 1, ttl)
+}
+
+func (c *client) Incr(key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(context.Background(), key, 1, ttl)
+}
+
+func (c *client) DecrWithContext(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(ctx, key, -1, ttl)
+}
+
+func (c *client) Decr(key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(context.Background(), key, -1, ttl)
+}
+
+func (c *client) IncrByWithContext(ctx context.Context, key string, value int64, ttl time.Duration) (int64, error) {
+	val, err := c.runOnCreate(ctx, key, ttl, "INCRBY", value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to incr cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) IncrBy(key string, value int64, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(context.Background(), key, value, ttl)
+}
+
+func (c *client) HIncrByWithContext(ctx context.Context, key, field string, value int64, ttl time.Duration) (int64, error) {
+	val, err := c.runOnCreate(ctx, key, ttl, "HINCRBY", field, value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to HIncrBy cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) HIncrBy(key, field string, value int64, ttl time.Duration) (int64, error) {
+	return c.HIncrByWithContext(context.Background(), key, field, value, ttl)
+}
+
+// PFAddWithContext adds elements to the HyperLogLog of key and reports whether
+// its estimated cardinality changed.
+func (c *client) PFAddWithContext(ctx context.Context, key string, ttl time.Duration, elements ...interface{}) (bool, error) {
+	val, err := c.runOnCreate(ctx, key, ttl, "PFADD", elements...)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to PFAdd cache with key %s!", key)
+	}
+	return val == 1, nil
+}
+
+func (c *client) PFAdd(key string, ttl time.Duration, elements ...interface{}) (bool, error) {
+	return c.PFAddWithContext(context.Background(), key, ttl, elements...)
+}
+
+func (c *client) PFCountWithContext(ctx context.Context, keys ...string) (int64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := r.PFCount(keys...).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to PFCount cache with keys %v!", keys)
+	}
+	return val, nil
+}
+
+func (c *client) PFCount(keys ...string) (int64, error) {
+	return c.PFCountWithContext(context.Background(), keys...)
+}
+
+// SetBitWithContext sets the bit at offset and returns its previous value.
+func (c *client) SetBitWithContext(ctx context.Context, key string, offset int64, value int, ttl time.Duration) (int64, error) {
+	val, err := c.runOnCreate(ctx, key, ttl, "SETBIT", offset, value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to SetBit cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) SetBit(key string, offset int64, value int, ttl time.Duration) (int64, error) {
+	return c.SetBitWithContext(context.Background(), key, offset, value, ttl)
+}
+
+func (c *client) GetBitWithContext(ctx context.Context, key string, offset int64) (int64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := r.GetBit(key, offset).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to GetBit cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) GetBit(key string, offset int64) (int64, error) {
+	return c.GetBitWithContext(context.Background(), key, offset)
+}
+
+func (c *client) BitCountWithContext(ctx context.Context, key string) (int64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := r.BitCount(key, nil).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to BitCount cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) BitCount(key string) (int64, error) {
+	return c.BitCountWithContext(context.Background(), key)
+}
+
+func (c *client) runOnCreate(ctx context.Context, key string, ttl time.Duration, command string, args ...interface{}) (int64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	argv := append([]interface{}{int64(ttl / time.Millisecond), command}, args...)
+	val, err := expireOnCreate.Run(r, []string{key}, argv...).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := val.(int64)
+	if !ok {
+		return 0, errors.Errorf("unexpected reply %v", val)
+	}
+	return n, nil
+}
@@ -0,0 +1,184 @@
+package rediscore
+
+import (
+	"context"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/codec"
+
+	gr "github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+type (
+	pipe struct {
+		instance gr.Pipeliner
+		codec    codec.Codec
+	}
+
+	// failedPipe is returned when the pipe can't be bound to its context, its
+	// commands and Exec report err without reaching the server.
+	failedPipe struct {
+		err error
+	}
+
+	valueResult struct {
+		key   string
+		cmd   *gr.StringCmd
+		codec codec.Codec
+	}
+)
+
+func (p *pipe) Set(key string, value interface{}) error {
+	return p.SetWithExpiration(key, value, 0)
+}
+
+func (p *pipe) SetWithExpiration(key string, value interface{}, expired time.Duration) error {
+	val, err := p.codec.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set cache with key %s!", key)
+	}
+
+	return p.instance.Set(key, val, expired).Err()
+}
+
+func (p *pipe) Get(key string) cache.ValueResult {
+	return &valueResult{key: key, cmd: p.instance.Get(key), codec: p.codec}
+}
+
+func (p *pipe) HSet(key, field string, value interface{}) error {
+	val, err := p.codec.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to HSet cache with key %s!", key)
+	}
+
+	return p.instance.HSet(key, field, val).Err()
+}
+
+func (p *pipe) HMSet(key string, value map[string]interface{}) error {
+	fields, err := marshalFields(p.codec, value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to HMSet cache with key %s!", key)
+	}
+
+	return p.instance.HMSet(key, fields).Err()
+}
+
+func (p *pipe) HGetAll(key string) cache.StringMapResult {
+	return p.instance.HGetAll(key)
+}
+
+func (p *pipe) Del(keys ...string) cache.IntResult {
+	return p.instance.Del(keys...)
+}
+
+// Expire sets the expiration in milliseconds, go-redis truncates EXPIRE to
+// seconds and a shorter expiration would remove the key.
+func (p *pipe) Expire(key string, expiration time.Duration) cache.BoolResult {
+	return p.instance.PExpire(key, expiration)
+}
+
+func (p *pipe) Incr(key string) cache.IntResult {
+	return p.instance.Incr(key)
+}
+
+func (p *pipe) ZAdd(key string, members ...gr.Z) cache.IntResult {
+	return p.instance.ZAdd(key, members...)
+}
+
+// ExecWithContext sends the queued commands, a missing key read by Get is
+// reported on its result rather than failing the whole pipe.
+func (p *pipe) ExecWithContext(ctx context.Context) error {
+	if err := alive(ctx); err != nil {
+		p.instance.Discard()
+		return err
+	}
+
+	cmds, err := p.instance.Exec()
+	if err == gr.Nil {
+		err = nil
+		for _, cmd := range cmds {
+			if e := cmd.Err(); e != nil && e != gr.Nil {
+				err = e
+				break
+			}
+		}
+	}
+
+	return errors.Wrapf(err, "failed to exec pipeline")
+}
+
+func (p *pipe) Exec() error {
+	return p.ExecWithContext(context.Background())
+}
+
+func (p *failedPipe) Set(key string, value interface{}) error {
+	return p.err
+}
+
+func (p *failedPipe) SetWithExpiration(key string, value interface{}, expired time.Duration) error {
+	return p.err
+}
+
+func (p *failedPipe) Get(key string) cache.ValueResult {
+	return &valueResult{key: key, cmd: gr.NewStringResult("", p.err)}
+}
+
+func (p *failedPipe) HSet(key, field string, value interface{}) error {
+	return p.err
+}
+
+func (p *failedPipe) HMSet(key string, value map[string]interface{}) error {
+	return p.err
+}
+
+func (p *failedPipe) HGetAll(key string) cache.StringMapResult {
+	return gr.NewStringStringMapResult(nil, p.err)
+}
+
+func (p *failedPipe) Del(keys ...string) cache.IntResult {
+	return gr.NewIntResult(0, p.err)
+}
+
+func (p *failedPipe) Expire(key string, expiration time.Duration) cache.BoolResult {
+	return gr.NewBoolResult(false, p.err)
+}
+
+func (p *failedPipe) Incr(key string) cache.IntResult {
+	return gr.NewIntResult(0, p.err)
+}
+
+func (p *failedPipe) ZAdd(key string, members ...gr.Z) cache.IntResult {
+	return gr.NewIntResult(0, p.err)
+}
+
+func (p *failedPipe) ExecWithContext(ctx context.Context) error {
+	return errors.Wrapf(p.err, "failed to exec pipeline")
+}
+
+func (p *failedPipe) Exec() error {
+	return p.ExecWithContext(context.Background())
+}
+
+func (r *valueResult) Err() error {
+	return r.cmd.Err()
+}
+
+func (r *valueResult) Scan(object interface{}) error {
+	val, err := r.cmd.Bytes()
+
+	if err == gr.Nil {
+		return errors.Wrapf(err, "key %s does not exits", r.key)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get key %s", r.key)
+	}
+
+	if err := r.codec.Unmarshal(val, object); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal object")
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package rediscore
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jajotz/utilities-golang/cache/instrument"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+func (c *client) ScanKeysWithContext(ctx context.Context, pattern string, count int64) ([]string, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	keys := make([]string, 0)
+	err = c.forEachNode(ctx, r, func(node *redis.Client) error {
+		return scan(ctx, node, pattern, count, func(batch []string) error {
+			mu.Lock()
+			keys = append(keys, batch...)
+			mu.Unlock()
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to scan redis pattern %s!", pattern)
+	}
+
+	return keys, nil
+}
+
+func (c *client) ScanKeys(pattern string, count int64) ([]string, error) {
+	return c.ScanKeysWithContext(context.Background(), pattern, count)
+}
+
+// UnlinkByPatternWithContext removes the keys matching pattern in batches of
+// count and returns how many were removed, the memory is reclaimed in the
+// background. The keys are removed with DEL on servers older than redis 4.0.
+func (c *client) UnlinkByPatternWithContext(ctx context.Context, pattern string, count int64) (int64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var noUnlink int32
+	var removed int64
+	err = c.forEachNode(ctx, r, func(node *redis.Client) error {
+		return scan(ctx, node, pattern, count, func(batch []string) error {
+			if atomic.LoadInt32(&noUnlink) == 0 {
+				n, err := remove(node, batch, true)
+				if !isUnknownCommand(err) {
+					atomic.AddInt64(&removed, n)
+					return err
+				}
+				atomic.StoreInt32(&noUnlink, 1)
+			}
+
+			n, err := remove(node, batch, false)
+			atomic.AddInt64(&removed, n)
+			return err
+		})
+	})
+
+	if err != nil {
+		return atomic.LoadInt64(&removed), errors.Wrapf(err, "failed to remove key with pattern %s!", pattern)
+	}
+
+	return removed, nil
+}
+
+func (c *client) UnlinkByPattern(pattern string, count int64) (int64, error) {
+	return c.UnlinkByPatternWithContext(context.Background(), pattern, count)
+}
+
+// remove unlinks or deletes keys with single key commands, the keys of a batch
+// can belong to different slots.
+func remove(node *redis.Client, keys []string, unlink bool) (int64, error) {
+	pipe := node.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		if unlink {
+			cmds[i] = pipe.Unlink(key)
+		} else {
+			cmds[i] = pipe.Del(key)
+		}
+	}
+
+	if _, err := pipe.Exec(); err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	for _, cmd := range cmds {
+		removed += cmd.Val()
+	}
+	return removed, nil
+}
+
+func isUnknownCommand(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "ERR unknown command")
+}
+
+// forEachNode calls fn with the node holding the keys, or concurrently with
+// every master when the client is a cluster. The masters are bound to ctx and
+// report their commands to the hooks like r.
+func (c *client) forEachNode(ctx context.Context, r redis.UniversalClient, fn func(node *redis.Client) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	switch client := r.(type) {
+	case *redis.ClusterClient:
+		return client.ForEachMaster(func(node *redis.Client) error {
+			node = node.WithContext(ctx)
+			instrument.Wrap(ctx, node, c.hooks)
+			return fn(node)
+		})
+	case *redis.Client:
+		return fn(client)
+	default:
+		return errors.Errorf("unsupported redis client %T", r)
+	}
+}
+
+// scan walks the SCAN cursor of node until it wraps around, fn receives every non empty batch.
+func scan(ctx context.Context, node *redis.Client, pattern string, count int64, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		if err := alive(ctx); err != nil {
+			return err
+		}
+
+		keys, next, err := node.Scan(cursor, pattern, count).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
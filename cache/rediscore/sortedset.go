@@ -0,0 +1,212 @@
+package rediscore
+
+import (
+	"context"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+func (c *client) ZAddWithContext(ctx context.Context, key string, members ...redis.Z) (int64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := r.ZAdd(key, members...).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to zadd cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) ZAdd(key string, members ...redis.Z) (int64, error) {
+	return c.ZAddWithContext(context.Background(), key, members...)
+}
+
+func (c *client) ZIncrByWithContext(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := r.ZIncrBy(key, increment, member).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to ZIncrBy cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) ZIncrBy(key string, increment float64, member string) (float64, error) {
+	return c.ZIncrByWithContext(context.Background(), key, increment, member)
+}
+
+func (c *client) ZRemWithContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := r.ZRem(key, members...).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to ZRem cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) ZRem(key string, members ...interface{}) (int64, error) {
+	return c.ZRemWithContext(context.Background(), key, members...)
+}
+
+func (c *client) ZRemRangeByRankWithContext(ctx context.Context, key string, start, stop int64) (int64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := r.ZRemRangeByRank(key, start, stop).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to ZRemRangeByRank cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) ZRemRangeByRank(key string, start, stop int64) (int64, error) {
+	return c.ZRemRangeByRankWithContext(context.Background(), key, start, stop)
+}
+
+func (c *client) ZRangeWithContext(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := r.ZRangeWithScores(key, start, stop).Result()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to zrange cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) ZRange(key string, start, stop int64) ([]redis.Z, error) {
+	return c.ZRangeWithContext(context.Background(), key, start, stop)
+}
+
+func (c *client) ZRevRangeWithContext(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := r.ZRevRangeWithScores(key, start, stop).Result()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to ZRevRange cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) ZRevRange(key string, start, stop int64) ([]redis.Z, error) {
+	return c.ZRevRangeWithContext(context.Background(), key, start, stop)
+}
+
+func (c *client) ZRangeByScoreWithContext(ctx context.Context, key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := r.ZRangeByScoreWithScores(key, opt).Result()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to ZRangeByScore cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) ZRangeByScore(key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	return c.ZRangeByScoreWithContext(context.Background(), key, opt)
+}
+
+func (c *client) ZRevRangeByScoreWithContext(ctx context.Context, key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := r.ZRevRangeByScoreWithScores(key, opt).Result()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to ZRevRangeByScore cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) ZRevRangeByScore(key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	return c.ZRevRangeByScoreWithContext(context.Background(), key, opt)
+}
+
+func (c *client) ZRankWithContext(ctx context.Context, key, member string) (int64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := r.ZRank(key, member).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to ZRank cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) ZRank(key, member string) (int64, error) {
+	return c.ZRankWithContext(context.Background(), key, member)
+}
+
+func (c *client) ZRevRankWithContext(ctx context.Context, key, member string) (int64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := r.ZRevRank(key, member).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to ZRevRank cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) ZRevRank(key, member string) (int64, error) {
+	return c.ZRevRankWithContext(context.Background(), key, member)
+}
+
+func (c *client) ZScoreWithContext(ctx context.Context, key, member string) (float64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := r.ZScore(key, member).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to ZScore cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) ZScore(key, member string) (float64, error) {
+	return c.ZScoreWithContext(context.Background(), key, member)
+}
+
+func (c *client) ZCardWithContext(ctx context.Context, key string) (int64, error) {
+	r, err := c.withContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := r.ZCard(key).Result()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to ZCard cache with key %s!", key)
+	}
+	return val, nil
+}
+
+func (c *client) ZCard(key string) (int64, error) {
+	return c.ZCardWithContext(context.Background(), key)
+}
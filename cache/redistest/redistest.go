@@ -0,0 +1,114 @@
+// Package redistest runs an in-process redis server for the tests of the
+// packages built on the redis caches.
+package redistest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/cachetest"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+type (
+	// Server is a RESP server backed by miniredis, its keys only expire when
+	// the clock is moved with FastForward.
+	Server struct {
+		*miniredis.Miniredis
+		mu    sync.Mutex
+		hooks []server.Hook
+	}
+)
+
+// Run starts a server listening on a random local port, the test fails when
+// it can't be started. Callers close it once the test is done.
+func Run(t testing.TB) *Server {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal("failed to start redis server ", err)
+	}
+
+	s := &Server{Miniredis: m}
+	m.Server().SetPreHook(s.preHook)
+	if err := s.fixCommand(); err != nil {
+		m.Close()
+		t.Fatal("failed to start redis server ", err)
+	}
+
+	return s
+}
+
+// AddPreHook runs hook before every command, after the hooks added before it.
+// A hook returning true has replied to the command and the next ones are
+// skipped. Use it instead of Server().SetPreHook, which drops the hooks of s.
+func (s *Server) AddPreHook(hook server.Hook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hooks = append(s.hooks, hook)
+}
+
+func (s *Server) preHook(c *server.Peer, cmd string, args ...string) bool {
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+
+	for _, hook := range hooks {
+		if hook(c, cmd, args...) {
+			return true
+		}
+	}
+	return false
+}
+
+// fixCommand replies COMMAND as an array, miniredis sends it as a bulk string
+// and the cluster clients can't find the keys of the commands without it, the
+// commands of a transaction would then be split in several transactions.
+func (s *Server) fixCommand() error {
+	r := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer func() { _ = r.Close() }()
+
+	reply, err := r.Do("COMMAND").Result()
+	if err != nil {
+		return err
+	}
+
+	text, ok := reply.(string)
+	if !ok {
+		// - COMMAND is already an array
+		return nil
+	}
+
+	// - the bulk string holds the RESP array with indented and \n ended lines
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+
+	if !strings.HasPrefix(lines[0], "*") {
+		return errors.Errorf("unexpected COMMAND reply %.20q", text)
+	}
+
+	raw := strings.Join(lines, "\r\n") + "\r\n"
+	s.AddPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+		if cmd != "COMMAND" || len(args) > 0 {
+			return false
+		}
+
+		c.WriteRaw(raw)
+		return true
+	})
+	return nil
+}
+
+// Conformance runs the cachetest suite on the caches created by newCache,
+// the expirations are elapsed by moving the server clock.
+func (s *Server) Conformance(t *testing.T, newCache func() (cache.Cache, error)) {
+	cachetest.Run(t, &cachetest.Option{New: newCache, Sleep: s.FastForward})
+}
@@ -0,0 +1,38 @@
+package redistest
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/go-redis/redis"
+)
+
+func Test_AddPreHook_keeps_command_fix(t *testing.T) {
+	s := Run(t)
+	defer s.Close()
+
+	s.AddPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+		if cmd != "PING" {
+			return false
+		}
+
+		c.WriteInline("HOOKED")
+		return true
+	})
+
+	r := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer func() { _ = r.Close() }()
+
+	if pong, err := r.Ping().Result(); err != nil || pong != "HOOKED" {
+		t.Errorf("expected the hook to reply, got %s %v", pong, err)
+	}
+
+	commands, err := r.Command().Result()
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if info, ok := commands["get"]; !ok || info.FirstKeyPos != 1 {
+		t.Errorf("expected COMMAND to be an array, got %d commands", len(commands))
+	}
+}
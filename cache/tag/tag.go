@@ -0,0 +1,267 @@
+package tag
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultPrefix      = "tag:"
+	DefaultBatchSize   = 500
+	DefaultSnapshotTTL = time.Hour
+)
+
+// addKey records ARGV[1] in the tag set and keeps the set alive at least as
+// long as the key, a zero ttl makes the set persistent.
+var addKey = redis.NewScript(`
+local added = redis.call("SADD", KEYS[1], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl <= 0 then
+	redis.call("PERSIST", KEYS[1])
+	return added
+end
+local current = redis.call("PTTL", KEYS[1])
+if (added == 1 and redis.call("SCARD", KEYS[1]) == 1) or (current >= 0 and current < ttl) then
+	redis.call("PEXPIRE", KEYS[1], ttl)
+end
+return added`)
+
+// snapshot renames the tag set of KEYS[1] to KEYS[2] in the same slot and
+// expires it after ARGV[1] milliseconds, it returns 0 when the tag has no key.
+var snapshot = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("RENAME", KEYS[1], KEYS[2])
+redis.call("PEXPIRE", KEYS[2], ARGV[1])
+return 1`)
+
+// invalidateAll deletes the members of the tag sets of KEYS and the sets in one
+// step, the members are not declared keys so it only runs on a single node.
+var invalidateAll = redis.NewScript(`
+local batch = tonumber(ARGV[1])
+for _, tag in ipairs(KEYS) do
+	local keys = redis.call("SMEMBERS", tag)
+	for i = 1, #keys, batch do
+		redis.call("DEL", unpack(keys, i, math.min(i + batch - 1, #keys)))
+	end
+	redis.call("DEL", tag)
+end
+return 1`)
+
+// restore moves the members left in the snapshot of KEYS[2] back to the tag
+// set of KEYS[1], which is then kept without expiration.
+var restore = redis.NewScript(`
+redis.call("SUNIONSTORE", KEYS[1], KEYS[1], KEYS[2])
+redis.call("DEL", KEYS[2])
+return 1`)
+
+type (
+	Option struct {
+		// Prefix is prepended to the key of every tag set.
+		Prefix string
+		// BatchSize is the number of keys deleted per command on invalidation.
+		BatchSize int64
+		// SnapshotTTL bounds the life of the set of keys being invalidated, in
+		// case the invalidation fails and its keys can't be put back in the tag.
+		SnapshotTTL time.Duration
+	}
+
+	Tagger interface {
+		SetWithTagsWithContext(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error
+		SetWithTags(key string, value interface{}, ttl time.Duration, tags ...string) error
+
+		// InvalidateTagsWithContext deletes every key carrying one of tags. It is
+		// atomic on a single node, standalone or sentinel. On a cluster the keys
+		// live on any node and are deleted in batches: when a batch fails the keys
+		// left are put back in the tag so the invalidation can be retried, and the
+		// keys tagged while the invalidation runs are kept.
+		InvalidateTagsWithContext(ctx context.Context, tags ...string) error
+		InvalidateTags(tags ...string) error
+	}
+
+	tagger struct {
+		option *Option
+		cache  cache.Cache
+		client redis.UniversalClient
+	}
+)
+
+func getOption(option *Option) error {
+	if option.Prefix == "" {
+		option.Prefix = DefaultPrefix
+	}
+
+	if option.BatchSize == 0 {
+		option.BatchSize = DefaultBatchSize
+	}
+
+	if option.SnapshotTTL == 0 {
+		option.SnapshotTTL = DefaultSnapshotTTL
+	}
+
+	return nil
+}
+
+// New creates a Tagger on top of one of the redis backed caches, values are
+// written through client so its codec is used.
+func New(client cache.Cache, option *Option) (Tagger, error) {
+	instance, ok := client.(cache.Instance)
+	if !ok {
+		return nil, errors.New("cache client is not backed by redis")
+	}
+
+	if err := getOption(option); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &tagger{option: option, cache: client, client: instance.Instance()}, nil
+}
+
+func (t *tagger) SetWithTagsWithContext(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// - record the tags first so a stored key is never missed by an invalidation
+	for _, tag := range tags {
+		if err := addKey.Run(t.client, []string{t.option.Prefix + tag}, key, int64(ttl/time.Millisecond)).Err(); err != nil {
+			return errors.Wrapf(err, "failed to tag cache with key %s!", key)
+		}
+	}
+
+	return t.cache.SetWithExpirationWithContext(ctx, key, value, ttl)
+}
+
+func (t *tagger) SetWithTags(key string, value interface{}, ttl time.Duration, tags ...string) error {
+	return t.SetWithTagsWithContext(context.Background(), key, value, ttl, tags...)
+}
+
+func (t *tagger) InvalidateTagsWithContext(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if _, ok := t.client.(*redis.ClusterClient); !ok {
+		if err := ctx.Err(); err != nil {
+			return errors.WithStack(err)
+		}
+
+		keys := make([]string, len(tags))
+		for i, tag := range tags {
+			keys[i] = t.option.Prefix + tag
+		}
+
+		if err := invalidateAll.Run(t.client, keys, t.option.BatchSize).Err(); err != nil {
+			return errors.Wrapf(err, "failed to invalidate tags %s!", strings.Join(tags, ", "))
+		}
+		return nil
+	}
+
+	for _, tag := range tags {
+		if err := ctx.Err(); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if err := t.invalidate(t.option.Prefix + tag); err != nil {
+			return errors.Wrapf(err, "failed to invalidate tag %s!", tag)
+		}
+	}
+
+	return nil
+}
+
+func (t *tagger) InvalidateTags(tags ...string) error {
+	return t.InvalidateTagsWithContext(context.Background(), tags...)
+}
+
+// invalidate renames the tag set of a cluster to a snapshot in the same slot,
+// then deletes its members with single key commands so they can live on any node.
+func (t *tagger) invalidate(tagKey string) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	snapshotKey := sameSlot(tagKey, ":invalidating:"+token)
+	ok, err := snapshot.Run(t.client, []string{tagKey, snapshotKey}, int64(t.option.SnapshotTTL/time.Millisecond)).Int64()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if ok == 0 {
+		return nil
+	}
+
+	if err := t.deleteMembers(snapshotKey); err != nil {
+		if e := restore.Run(t.client, []string{tagKey, snapshotKey}).Err(); e != nil {
+			return errors.Wrapf(err, "failed to delete keys of tag %s, restoring it failed with %s!", tagKey, e.Error())
+		}
+		return err
+	}
+
+	return errors.WithStack(t.client.Del(snapshotKey).Err())
+}
+
+// deleteMembers deletes the keys of the snapshot, the deleted keys are removed
+// from it so they are not restored on failure.
+func (t *tagger) deleteMembers(snapshotKey string) error {
+	for {
+		keys, err := t.client.SRandMemberN(snapshotKey, t.option.BatchSize).Result()
+		if err == redis.Nil {
+			return nil
+		}
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if len(keys) == 0 {
+			return nil
+		}
+
+		pipe := t.client.Pipeline()
+		for _, key := range keys {
+			pipe.Del(key)
+		}
+		if _, err := pipe.Exec(); err != nil {
+			return errors.WithStack(err)
+		}
+
+		members := make([]interface{}, len(keys))
+		for i, key := range keys {
+			members[i] = key
+		}
+		if err := t.client.SRem(snapshotKey, members...).Err(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+}
+
+// sameSlot appends suffix to key so the result hashes to the same cluster slot.
+func sameSlot(key, suffix string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key + suffix
+		}
+	}
+
+	return "{" + key + "}" + suffix
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate invalidation token")
+	}
+
+	return hex.EncodeToString(b), nil
+}
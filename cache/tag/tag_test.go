@@ -0,0 +1,193 @@
+package tag
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/redis"
+	rediscluster "github.com/jajotz/utilities-golang/cache/redis-cluster"
+	"github.com/jajotz/utilities-golang/cache/redistest"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (r *recorder) BeforeProcess(ctx context.Context, cmd *cache.Command) context.Context {
+	return ctx
+}
+
+func (r *recorder) AfterProcess(ctx context.Context, cmd *cache.Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = append(r.names, cmd.Name)
+}
+
+func (r *recorder) BeforeProcessPipeline(ctx context.Context, cmds []*cache.Command) context.Context {
+	return ctx
+}
+
+func (r *recorder) AfterProcessPipeline(ctx context.Context, cmds []*cache.Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cmd := range cmds {
+		r.names = append(r.names, cmd.Name)
+	}
+}
+
+func Test_sameSlot_keeps_hash_tag(t *testing.T) {
+	cases := map[string]string{
+		"tag:user":          "{tag:user}:tmp",
+		"{tenant}:tag:user": "{tenant}:tag:user:tmp",
+	}
+
+	for key, expected := range cases {
+		if actual := sameSlot(key, ":tmp"); actual != expected {
+			t.Errorf("expected %s, got %s", expected, actual)
+		}
+	}
+}
+
+func newTagger(t *testing.T, hooks ...cache.Hook) (*tagger, *redistest.Server) {
+	return newTaggerOn(t, func(server *redistest.Server) (cache.Cache, error) {
+		return redis.New(&redis.Option{Address: server.Addr(), Hooks: hooks})
+	})
+}
+
+func newClusterTagger(t *testing.T, hooks ...cache.Hook) (*tagger, *redistest.Server) {
+	return newTaggerOn(t, func(server *redistest.Server) (cache.Cache, error) {
+		return rediscluster.New(&rediscluster.Option{Address: []string{server.Addr()}, Hooks: hooks})
+	})
+}
+
+func newTaggerOn(t *testing.T, newCache func(server *redistest.Server) (cache.Cache, error)) (*tagger, *redistest.Server) {
+	server := redistest.Run(t)
+
+	client, err := newCache(server)
+	if err != nil {
+		server.Close()
+		t.Fatal("should not error ", err)
+	}
+
+	tg, err := New(client, &Option{BatchSize: 2})
+	if err != nil {
+		server.Close()
+		t.Fatal("should not error ", err)
+	}
+
+	return tg.(*tagger), server
+}
+
+func Test_InvalidateTags_deletes_tagged_keys(t *testing.T) {
+	for name, newTagger := range map[string]func(*testing.T, ...cache.Hook) (*tagger, *redistest.Server){
+		"standalone": newTagger,
+		"cluster":    newClusterTagger,
+	} {
+		newTagger := newTagger
+		t.Run(name, func(t *testing.T) {
+			tg, server := newTagger(t)
+			defer server.Close()
+
+			testInvalidateTags(t, tg, server)
+		})
+	}
+}
+
+func testInvalidateTags(t *testing.T, tg *tagger, server *redistest.Server) {
+	for key, tags := range map[string][]string{
+		"user:1":  {"users"},
+		"user:2":  {"users", "admins"},
+		"user:3":  {"users"},
+		"order:1": {"orders"},
+	} {
+		if err := tg.SetWithTags(key, "value", time.Minute, tags...); err != nil {
+			t.Fatal("should not error ", err)
+		}
+	}
+
+	if err := tg.InvalidateTags("users", "missing"); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	for _, key := range []string{"user:1", "user:2", "user:3", "tag:users"} {
+		if server.Exists(key) {
+			t.Errorf("expected %s to be deleted", key)
+		}
+	}
+
+	if !server.Exists("order:1") || !server.Exists("tag:orders") {
+		t.Error("keys of other tags should be kept")
+	}
+
+	if keys := server.Keys(); len(keys) != 3 {
+		t.Errorf("expected no snapshot left, got %v", keys)
+	}
+}
+
+func Test_InvalidateTags_runs_one_script_on_single_node(t *testing.T) {
+	hook := &recorder{}
+	tg, server := newTagger(t, hook)
+	defer server.Close()
+
+	for _, key := range []string{"user:1", "user:2", "user:3", "user:4", "user:5"} {
+		if err := tg.SetWithTags(key, "value", time.Minute, "users", "active"); err != nil {
+			t.Fatal("should not error ", err)
+		}
+	}
+
+	hook.names = nil
+	if err := tg.InvalidateTags("users", "active"); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if len(hook.names) == 0 {
+		t.Fatal("expected the invalidation to be reported to the hooks")
+	}
+
+	// - the script is loaded on its first run
+	for _, name := range hook.names {
+		if name != "evalsha" && name != "eval" {
+			t.Errorf("expected a single script, got %v", hook.names)
+			break
+		}
+	}
+
+	if keys := server.Keys(); len(keys) != 0 {
+		t.Errorf("expected every key to be deleted, got %v", keys)
+	}
+}
+
+func Test_restore_puts_keys_back_in_tag(t *testing.T) {
+	tg, server := newClusterTagger(t)
+	defer server.Close()
+
+	if err := tg.SetWithTags("user:1", "value", time.Minute, "users"); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	snapshotKey := sameSlot("tag:users", ":invalidating:test")
+	if ok, err := snapshot.Run(tg.client, []string{"tag:users", snapshotKey}, int64(time.Hour/time.Millisecond)).Int64(); err != nil || ok != 1 {
+		t.Fatal("should not error ", ok, err)
+	}
+
+	if ttl := server.TTL(snapshotKey); ttl != time.Hour {
+		t.Errorf("expected snapshot to expire in 1h, got %s", ttl)
+	}
+
+	if err := tg.SetWithTags("user:2", "value", time.Minute, "users"); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if err := restore.Run(tg.client, []string{"tag:users", snapshotKey}).Err(); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	members, err := server.Members("tag:users")
+	if err != nil || len(members) != 2 || server.Exists(snapshotKey) {
+		t.Errorf("expected both keys back in the tag, got %v %v", members, err)
+	}
+}
@@ -0,0 +1,505 @@
+package tiered
+
+import (
+	"context"
+	"encoding"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/codec"
+	"github.com/jajotz/utilities-golang/logs"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultSize = 1024
+	DefaultTTL  = 5 * time.Second
+
+	invalidateKey     = "k:"
+	invalidatePattern = "p:"
+	invalidateAll     = "a:"
+)
+
+type (
+	Option struct {
+		// Size is the maximum number of keys kept in the local layer.
+		Size int
+		// TTL is how long a key is served locally before it is read again from the remote cache.
+		TTL time.Duration
+		// Channel enables invalidation across instances through pub/sub,
+		// the remote cache must implement cache.PubSub when it is set.
+		Channel string
+		Log     logs.Logger
+	}
+
+	tieredClient struct {
+		option       *Option
+		remote       cache.Cache
+		local        *lru
+		subscription cache.Subscription
+		done         chan struct{}
+		closeOnce    sync.Once
+	}
+)
+
+func getOption(option *Option) error {
+	if option.Size < 0 {
+		return errors.New("invalid local cache size")
+	}
+
+	if option.Size == 0 {
+		option.Size = DefaultSize
+	}
+
+	if option.TTL == 0 {
+		option.TTL = DefaultTTL
+	}
+
+	if option.Log == nil {
+		logger, _ := logs.DefaultLog()
+		option.Log = logger
+	}
+
+	return nil
+}
+
+// New wraps remote with a size bounded in-process LRU layer. Reads are served
+// from the local layer when possible, writes go to remote and invalidate the
+// local copy. The counter, sorted set, scanner and pub/sub interfaces are
+// implemented when remote implements them.
+func New(remote cache.Cache, option *Option) (cache.Cache, error) {
+	if remote == nil {
+		return nil, errors.New("remote cache is required")
+	}
+
+	if err := getOption(option); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	c := &tieredClient{
+		option: option,
+		remote: remote,
+		local:  newLRU(option.Size, option.TTL),
+		done:   make(chan struct{}),
+	}
+
+	if option.Channel != "" {
+		ps, ok := remote.(cache.PubSub)
+		if !ok {
+			return nil, errors.New("remote cache does not support pub/sub invalidation")
+		}
+
+		subscription, err := ps.Subscribe(option.Channel)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to subscribe to channel %s", option.Channel)
+		}
+		c.subscription = subscription
+
+		go c.listen()
+	}
+
+	return withCompanions(c), nil
+}
+
+func (c *tieredClient) listen() {
+	messages := c.subscription.Messages()
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			c.apply(msg.Payload)
+		}
+	}
+}
+
+func (c *tieredClient) apply(payload string) {
+	switch {
+	case strings.HasPrefix(payload, invalidateKey):
+		c.local.remove(strings.TrimPrefix(payload, invalidateKey))
+	case strings.HasPrefix(payload, invalidatePattern):
+		c.local.removeByPattern(strings.TrimPrefix(payload, invalidatePattern))
+	case strings.HasPrefix(payload, invalidateAll):
+		c.local.clear()
+	}
+}
+
+// invalidate drops the local copy and, when enabled, notifies the other instances.
+func (c *tieredClient) invalidate(payload string) {
+	c.apply(payload)
+
+	if c.option.Channel == "" {
+		return
+	}
+
+	if err := c.remote.(cache.PubSub).Publish(c.option.Channel, payload); err != nil {
+		c.option.Log.Errorf("failed to publish cache invalidation %s: %s", payload, err.Error())
+	}
+}
+
+func (c *tieredClient) PingWithContext(ctx context.Context) error {
+	return c.remote.PingWithContext(ctx)
+}
+
+func (c *tieredClient) Ping() error {
+	return c.PingWithContext(context.Background())
+}
+
+func (c *tieredClient) SetWithExpirationWithContext(ctx context.Context, key string, value interface{}, duration time.Duration) error {
+	defer c.invalidate(invalidateKey + key)
+	return c.remote.SetWithExpirationWithContext(ctx, key, value, duration)
+}
+
+func (c *tieredClient) SetWithExpiration(key string, value interface{}, duration time.Duration) error {
+	return c.SetWithExpirationWithContext(context.Background(), key, value, duration)
+}
+
+func (c *tieredClient) SetWithContext(ctx context.Context, key string, value interface{}) error {
+	defer c.invalidate(invalidateKey + key)
+	return c.remote.SetWithContext(ctx, key, value)
+}
+
+func (c *tieredClient) Set(key string, value interface{}) error {
+	return c.SetWithContext(context.Background(), key, value)
+}
+
+func (c *tieredClient) GetWithContext(ctx context.Context, key string, data interface{}) error {
+	decoder, ok := c.remote.(cache.Decoder)
+	if !ok {
+		return c.getBinary(ctx, key, data)
+	}
+
+	if e, ok := c.local.get(key); ok && e.value != nil {
+		return decoder.Decode(*e.value, data)
+	}
+
+	// - read the raw value so the local layer does not depend on the remote codec
+	revision := c.local.current()
+	val, err := c.remote.MGetWithContext(ctx, []string{key})
+	if err != nil {
+		return err
+	}
+
+	value, ok := val[0].(string)
+	if !ok {
+		return errors.Wrapf(redis.Nil, "key %s does not exits", key)
+	}
+
+	c.local.add(revision, entry{key: key, value: &value})
+	if err := decoder.Decode(value, data); err != nil {
+		return errors.Wrapf(err, "failed to get cache with key %s!", key)
+	}
+
+	return nil
+}
+
+func (c *tieredClient) Get(key string, data interface{}) error {
+	return c.GetWithContext(context.Background(), key, data)
+}
+
+func (c *tieredClient) getBinary(ctx context.Context, key string, data interface{}) error {
+	unmarshaler, ok := data.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return c.remote.GetWithContext(ctx, key, data)
+	}
+
+	if e, ok := c.local.get(key); ok && e.value != nil {
+		return unmarshaler.UnmarshalBinary([]byte(*e.value))
+	}
+
+	revision := c.local.current()
+	if err := c.remote.GetWithContext(ctx, key, data); err != nil {
+		return err
+	}
+
+	if marshaler, ok := data.(encoding.BinaryMarshaler); ok {
+		if b, err := marshaler.MarshalBinary(); err == nil {
+			value := string(b)
+			c.local.add(revision, entry{key: key, value: &value})
+		}
+	}
+
+	return nil
+}
+
+func (c *tieredClient) KeysWithContext(ctx context.Context, pattern string) ([]string, error) {
+	return c.remote.KeysWithContext(ctx, pattern)
+}
+
+func (c *tieredClient) Keys(pattern string) ([]string, error) {
+	return c.KeysWithContext(context.Background(), pattern)
+}
+
+func (c *tieredClient) RemoveWithContext(ctx context.Context, key string) error {
+	defer c.invalidate(invalidateKey + key)
+	return c.remote.RemoveWithContext(ctx, key)
+}
+
+func (c *tieredClient) Remove(key string) error {
+	return c.RemoveWithContext(context.Background(), key)
+}
+
+func (c *tieredClient) RemoveByPatternWithContext(ctx context.Context, pattern string, countPerLoop int64) error {
+	defer c.invalidate(invalidatePattern + pattern)
+	return c.remote.RemoveByPatternWithContext(ctx, pattern, countPerLoop)
+}
+
+func (c *tieredClient) RemoveByPattern(pattern string, countPerLoop int64) error {
+	return c.RemoveByPatternWithContext(context.Background(), pattern, countPerLoop)
+}
+
+func (c *tieredClient) FlushDatabaseWithContext(ctx context.Context) error {
+	defer c.invalidate(invalidateAll)
+	return c.remote.FlushDatabaseWithContext(ctx)
+}
+
+func (c *tieredClient) FlushDatabase() error {
+	return c.FlushDatabaseWithContext(context.Background())
+}
+
+func (c *tieredClient) FlushAllWithContext(ctx context.Context) error {
+	defer c.invalidate(invalidateAll)
+	return c.remote.FlushAllWithContext(ctx)
+}
+
+func (c *tieredClient) FlushAll() error {
+	return c.FlushAllWithContext(context.Background())
+}
+
+func (c *tieredClient) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		if c.subscription != nil {
+			if e := c.subscription.Close(); e != nil {
+				c.option.Log.Error(e)
+			}
+		}
+
+		err = c.remote.Close()
+	})
+
+	return err
+}
+
+func (c *tieredClient) SetZSetWithExpirationWithContext(ctx context.Context, key string, duration time.Duration, data ...redis.Z) error {
+	defer c.invalidate(invalidateKey + key)
+	return c.remote.SetZSetWithExpirationWithContext(ctx, key, duration, data...)
+}
+
+func (c *tieredClient) SetZSetWithExpiration(key string, duration time.Duration, data ...redis.Z) error {
+	return c.SetZSetWithExpirationWithContext(context.Background(), key, duration, data...)
+}
+
+func (c *tieredClient) SetZSetWithContext(ctx context.Context, key string, data ...redis.Z) error {
+	defer c.invalidate(invalidateKey + key)
+	return c.remote.SetZSetWithContext(ctx, key, data...)
+}
+
+func (c *tieredClient) SetZSet(key string, data ...redis.Z) error {
+	return c.SetZSetWithContext(context.Background(), key, data...)
+}
+
+func (c *tieredClient) GetZSetWithContext(ctx context.Context, key string) ([]redis.Z, error) {
+	if e, ok := c.local.get(key); ok && e.zset != nil {
+		data := make([]redis.Z, len(e.zset))
+		copy(data, e.zset)
+		return data, nil
+	}
+
+	revision := c.local.current()
+	data, err := c.remote.GetZSetWithContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	zset := make([]redis.Z, len(data))
+	copy(zset, data)
+	c.local.add(revision, entry{key: key, zset: zset})
+
+	return data, nil
+}
+
+func (c *tieredClient) GetZSet(key string) ([]redis.Z, error) {
+	return c.GetZSetWithContext(context.Background(), key)
+}
+
+func (c *tieredClient) HMSetWithExpirationWithContext(ctx context.Context, key string, value map[string]interface{}, ttl time.Duration) error {
+	defer c.invalidate(invalidateKey + key)
+	return c.remote.HMSetWithExpirationWithContext(ctx, key, value, ttl)
+}
+
+func (c *tieredClient) HMSetWithExpiration(key string, value map[string]interface{}, ttl time.Duration) error {
+	return c.HMSetWithExpirationWithContext(context.Background(), key, value, ttl)
+}
+
+func (c *tieredClient) HMSetWithContext(ctx context.Context, key string, value map[string]interface{}) error {
+	defer c.invalidate(invalidateKey + key)
+	return c.remote.HMSetWithContext(ctx, key, value)
+}
+
+func (c *tieredClient) HMSet(key string, value map[string]interface{}) error {
+	return c.HMSetWithContext(context.Background(), key, value)
+}
+
+func (c *tieredClient) HSetWithExpirationWithContext(ctx context.Context, key, field string, value interface{}, ttl time.Duration) error {
+	defer c.invalidate(invalidateKey + key)
+	return c.remote.HSetWithExpirationWithContext(ctx, key, field, value, ttl)
+}
+
+func (c *tieredClient) HSetWithExpiration(key, field string, value interface{}, ttl time.Duration) error {
+	return c.HSetWithExpirationWithContext(context.Background(), key, field, value, ttl)
+}
+
+func (c *tieredClient) HSetWithContext(ctx context.Context, key, field string, value interface{}) error {
+	defer c.invalidate(invalidateKey + key)
+	return c.remote.HSetWithContext(ctx, key, field, value)
+}
+
+func (c *tieredClient) HSet(key, field string, value interface{}) error {
+	return c.HSetWithContext(context.Background(), key, field, value)
+}
+
+func (c *tieredClient) HMGetWithContext(ctx context.Context, key string, fields ...string) ([]interface{}, error) {
+	e, ok := c.local.get(key)
+	if !ok || e.hash == nil {
+		return c.remote.HMGetWithContext(ctx, key, fields...)
+	}
+
+	val := make([]interface{}, len(fields))
+	for i, field := range fields {
+		if v, ok := e.hash[field]; ok {
+			val[i] = v
+		}
+	}
+
+	return val, nil
+}
+
+func (c *tieredClient) HMGet(key string, fields ...string) ([]interface{}, error) {
+	return c.HMGetWithContext(context.Background(), key, fields...)
+}
+
+func (c *tieredClient) HGetAllWithContext(ctx context.Context, key string) (map[string]string, error) {
+	if e, ok := c.local.get(key); ok && e.hash != nil {
+		return copyHash(e.hash), nil
+	}
+
+	revision := c.local.current()
+	val, err := c.remote.HGetAllWithContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.local.add(revision, entry{key: key, hash: copyHash(val)})
+	return val, nil
+}
+
+func (c *tieredClient) HGetAll(key string) (map[string]string, error) {
+	return c.HGetAllWithContext(context.Background(), key)
+}
+
+func (c *tieredClient) HGetWithContext(ctx context.Context, key, field string, response interface{}) error {
+	e, ok := c.local.get(key)
+	if !ok || e.hash == nil {
+		return c.remote.HGetWithContext(ctx, key, field, response)
+	}
+
+	val, ok := e.hash[field]
+	if !ok {
+		return errors.Wrapf(redis.Nil, "key %s does not exits", key)
+	}
+
+	return c.Decode(val, response)
+}
+
+func (c *tieredClient) HGet(key, field string, response interface{}) error {
+	return c.HGetWithContext(context.Background(), key, field, response)
+}
+
+func (c *tieredClient) MGetWithContext(ctx context.Context, key []string) ([]interface{}, error) {
+	val := make([]interface{}, len(key))
+	missing := make([]string, 0)
+	index := make([]int, 0)
+
+	for i, k := range key {
+		if e, ok := c.local.get(k); ok && e.value != nil {
+			val[i] = *e.value
+			continue
+		}
+		missing = append(missing, k)
+		index = append(index, i)
+	}
+
+	if len(missing) == 0 {
+		return val, nil
+	}
+
+	revision := c.local.current()
+	remote, err := c.remote.MGetWithContext(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range remote {
+		val[index[i]] = v
+		if s, ok := v.(string); ok {
+			c.local.add(revision, entry{key: missing[i], value: &s})
+		}
+	}
+
+	return val, nil
+}
+
+func (c *tieredClient) MGet(key []string) ([]interface{}, error) {
+	return c.MGetWithContext(context.Background(), key)
+}
+
+func (c *tieredClient) Decode(value interface{}, object interface{}) error {
+	if decoder, ok := c.remote.(cache.Decoder); ok {
+		return decoder.Decode(value, object)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return redis.Nil
+	}
+
+	return codec.Binary.Unmarshal([]byte(s), object)
+}
+
+func (c *tieredClient) Client() cache.Cache {
+	return c
+}
+
+func (c *tieredClient) PipelineWithContext(ctx context.Context) cache.Pipe {
+	return &pipe{client: c, instance: c.remote.PipelineWithContext(ctx)}
+}
+
+func (c *tieredClient) Pipeline() cache.Pipe {
+	return c.PipelineWithContext(context.Background())
+}
+
+func (c *tieredClient) TxPipelineWithContext(ctx context.Context) cache.Pipe {
+	return &pipe{client: c, instance: c.remote.TxPipelineWithContext(ctx)}
+}
+
+func (c *tieredClient) TxPipeline() cache.Pipe {
+	return c.TxPipelineWithContext(context.Background())
+}
+
+func copyHash(hash map[string]string) map[string]string {
+	val := make(map[string]string, len(hash))
+	for k, v := range hash {
+		val[k] = v
+	}
+	return val
+}
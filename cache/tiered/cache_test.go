@@ -0,0 +1,208 @@
+package tiered
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/cache/memory"
+
+	"github.com/go-redis/redis"
+)
+
+type testValue struct {
+	value string
+}
+
+func (v *testValue) MarshalBinary() ([]byte, error) {
+	return []byte(v.value), nil
+}
+
+func (v *testValue) UnmarshalBinary(data []byte) error {
+	v.value = string(data)
+	return nil
+}
+
+func Test_Get_served_from_local_layer(t *testing.T) {
+	remote := memory.New()
+	c, err := New(remote, &Option{TTL: time.Minute})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	_ = c.Set("key", "value")
+	if err := c.Get("key", &testValue{}); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	// - change remote directly, local layer still holds the old value
+	_ = remote.Set("key", "changed")
+
+	object := testValue{}
+	_ = c.Get("key", &object)
+	if object.value != "value" {
+		t.Errorf("expected local value, got %s", object.value)
+	}
+}
+
+func Test_Set_invalidates_local_layer(t *testing.T) {
+	c, _ := New(memory.New(), &Option{TTL: time.Minute})
+
+	_ = c.Set("key", "value")
+	_ = c.Get("key", &testValue{})
+	_ = c.Set("key", "changed")
+
+	object := testValue{}
+	_ = c.Get("key", &object)
+	if object.value != "changed" {
+		t.Errorf("expected changed, got %s", object.value)
+	}
+}
+
+func Test_local_layer_is_size_bounded(t *testing.T) {
+	l := newLRU(2, time.Minute)
+	for _, key := range []string{"a", "b", "c"} {
+		value := key
+		l.add(l.current(), entry{key: key, value: &value})
+	}
+
+	if _, ok := l.get("a"); ok {
+		t.Error("least recently used key should be evicted")
+	}
+
+	if _, ok := l.get("c"); !ok {
+		t.Error("most recent key should be kept")
+	}
+}
+
+func Test_Channel_invalidates_other_instances(t *testing.T) {
+	remote := memory.New()
+	first, err := New(remote, &Option{Channel: "invalidate"})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	second, err := New(remote, &Option{Channel: "invalidate"})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	_ = first.Set("key", "old")
+	if err := second.Get("key", &testValue{}); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	_ = first.Set("key", "new")
+	time.Sleep(20 * time.Millisecond)
+
+	value := &testValue{}
+	if err := second.Get("key", value); err != nil || value.value != "new" {
+		t.Errorf("expected invalidated value, got %+v %v", value, err)
+	}
+}
+
+func Test_forwards_companion_interfaces(t *testing.T) {
+	c, err := New(memory.New(), &Option{TTL: time.Minute})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+	defer c.Close()
+
+	if _, ok := c.(cache.Counter); !ok {
+		t.Error("expected counter to be forwarded")
+	}
+
+	if _, ok := c.(cache.SortedSet); !ok {
+		t.Error("expected sorted set to be forwarded")
+	}
+
+	if _, ok := c.(cache.Scanner); !ok {
+		t.Error("expected scanner to be forwarded")
+	}
+
+	if _, ok := c.(cache.PubSub); !ok {
+		t.Error("expected pub/sub to be forwarded")
+	}
+}
+
+func Test_exposes_only_companion_interfaces_of_remote(t *testing.T) {
+	remote := memory.New()
+	c, err := New(struct {
+		cache.Cache
+		cache.SortedSet
+	}{remote, remote.(cache.SortedSet)}, &Option{TTL: time.Minute})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+	defer c.Close()
+
+	if _, ok := c.(cache.Counter); ok {
+		t.Error("counter should not be exposed")
+	}
+
+	if _, ok := c.(cache.Scanner); ok {
+		t.Error("scanner should not be exposed")
+	}
+
+	if _, ok := c.(cache.PubSub); ok {
+		t.Error("pub/sub should not be exposed")
+	}
+
+	sortedSet, ok := c.(cache.SortedSet)
+	if !ok {
+		t.Fatal("expected sorted set to be forwarded")
+	}
+
+	if _, err := sortedSet.ZAdd("zset", redis.Z{Score: 1, Member: "a"}); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	if err := c.Set("key", "value"); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	plain, err := New(struct{ cache.Cache }{remote}, &Option{TTL: time.Minute})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+	defer plain.Close()
+
+	if _, ok := plain.(cache.SortedSet); ok {
+		t.Error("sorted set should not be exposed")
+	}
+}
+
+func Test_IncrBy_invalidates_local_layer(t *testing.T) {
+	c, _ := New(memory.New(), &Option{TTL: time.Minute})
+	defer c.Close()
+
+	counter := c.(cache.Counter)
+	if _, err := counter.IncrBy("counter", 1, time.Minute); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if err := c.Get("counter", &testValue{}); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if _, err := counter.IncrBy("counter", 2, time.Minute); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	value := &testValue{}
+	if err := c.Get("counter", value); err != nil || value.value != "3" {
+		t.Errorf("expected 3, got %+v %v", value, err)
+	}
+}
+
+func Test_Close_twice(t *testing.T) {
+	c, _ := New(memory.New(), &Option{Channel: "invalidate"})
+
+	if err := c.Close(); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Error("should not error ", err)
+	}
+}
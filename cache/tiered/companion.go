@@ -0,0 +1,342 @@
+package tiered
+
+import (
+	"context"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+)
+
+// The companion interfaces are forwarded to the remote cache, New only exposes
+// the ones it implements. Their writes invalidate the local copy.
+
+type (
+	counterClient struct {
+		*tieredClient
+		counter cache.Counter
+	}
+
+	sortedSetClient struct {
+		*tieredClient
+		sortedSet cache.SortedSet
+	}
+
+	scannerClient struct {
+		*tieredClient
+		scanner cache.Scanner
+	}
+
+	pubSubClient struct {
+		*tieredClient
+		ps cache.PubSub
+	}
+)
+
+// withCompanions returns c along with the companion interfaces remote implements.
+func withCompanions(c *tieredClient) cache.Cache {
+	counter, hasCounter := c.remote.(cache.Counter)
+	sortedSet, hasSortedSet := c.remote.(cache.SortedSet)
+	scanner, hasScanner := c.remote.(cache.Scanner)
+	ps, hasPubSub := c.remote.(cache.PubSub)
+
+	cc := &counterClient{tieredClient: c, counter: counter}
+	zc := &sortedSetClient{tieredClient: c, sortedSet: sortedSet}
+	sc := &scannerClient{tieredClient: c, scanner: scanner}
+	pc := &pubSubClient{tieredClient: c, ps: ps}
+
+	switch {
+	case hasCounter && hasSortedSet && hasScanner && hasPubSub:
+		return &struct {
+			*tieredClient
+			*counterClient
+			*sortedSetClient
+			*scannerClient
+			*pubSubClient
+		}{c, cc, zc, sc, pc}
+	case hasCounter && hasSortedSet && hasScanner:
+		return &struct {
+			*tieredClient
+			*counterClient
+			*sortedSetClient
+			*scannerClient
+		}{c, cc, zc, sc}
+	case hasCounter && hasSortedSet && hasPubSub:
+		return &struct {
+			*tieredClient
+			*counterClient
+			*sortedSetClient
+			*pubSubClient
+		}{c, cc, zc, pc}
+	case hasCounter && hasScanner && hasPubSub:
+		return &struct {
+			*tieredClient
+			*counterClient
+			*scannerClient
+			*pubSubClient
+		}{c, cc, sc, pc}
+	case hasSortedSet && hasScanner && hasPubSub:
+		return &struct {
+			*tieredClient
+			*sortedSetClient
+			*scannerClient
+			*pubSubClient
+		}{c, zc, sc, pc}
+	case hasCounter && hasSortedSet:
+		return &struct {
+			*tieredClient
+			*counterClient
+			*sortedSetClient
+		}{c, cc, zc}
+	case hasCounter && hasScanner:
+		return &struct {
+			*tieredClient
+			*counterClient
+			*scannerClient
+		}{c, cc, sc}
+	case hasCounter && hasPubSub:
+		return &struct {
+			*tieredClient
+			*counterClient
+			*pubSubClient
+		}{c, cc, pc}
+	case hasSortedSet && hasScanner:
+		return &struct {
+			*tieredClient
+			*sortedSetClient
+			*scannerClient
+		}{c, zc, sc}
+	case hasSortedSet && hasPubSub:
+		return &struct {
+			*tieredClient
+			*sortedSetClient
+			*pubSubClient
+		}{c, zc, pc}
+	case hasScanner && hasPubSub:
+		return &struct {
+			*tieredClient
+			*scannerClient
+			*pubSubClient
+		}{c, sc, pc}
+	case hasCounter:
+		return cc
+	case hasSortedSet:
+		return zc
+	case hasScanner:
+		return sc
+	case hasPubSub:
+		return pc
+	default:
+		return c
+	}
+}
+
+func (c *counterClient) IncrWithContext(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(ctx, key, 1, ttl)
+}
+
+func (c *counterClient) Incr(key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(context.Background(), key, 1, ttl)
+}
+
+func (c *counterClient) DecrWithContext(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(ctx, key, -1, ttl)
+}
+
+func (c *counterClient) Decr(key string, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(context.Background(), key, -1, ttl)
+}
+
+func (c *counterClient) IncrByWithContext(ctx context.Context, key string, value int64, ttl time.Duration) (int64, error) {
+	defer c.invalidate(invalidateKey + key)
+	return c.counter.IncrByWithContext(ctx, key, value, ttl)
+}
+
+func (c *counterClient) IncrBy(key string, value int64, ttl time.Duration) (int64, error) {
+	return c.IncrByWithContext(context.Background(), key, value, ttl)
+}
+
+func (c *counterClient) HIncrByWithContext(ctx context.Context, key, field string, value int64, ttl time.Duration) (int64, error) {
+	defer c.invalidate(invalidateKey + key)
+	return c.counter.HIncrByWithContext(ctx, key, field, value, ttl)
+}
+
+func (c *counterClient) HIncrBy(key, field string, value int64, ttl time.Duration) (int64, error) {
+	return c.HIncrByWithContext(context.Background(), key, field, value, ttl)
+}
+
+func (c *counterClient) PFAddWithContext(ctx context.Context, key string, ttl time.Duration, elements ...interface{}) (bool, error) {
+	defer c.invalidate(invalidateKey + key)
+	return c.counter.PFAddWithContext(ctx, key, ttl, elements...)
+}
+
+func (c *counterClient) PFAdd(key string, ttl time.Duration, elements ...interface{}) (bool, error) {
+	return c.PFAddWithContext(context.Background(), key, ttl, elements...)
+}
+
+func (c *counterClient) PFCountWithContext(ctx context.Context, keys ...string) (int64, error) {
+	return c.counter.PFCountWithContext(ctx, keys...)
+}
+
+func (c *counterClient) PFCount(keys ...string) (int64, error) {
+	return c.PFCountWithContext(context.Background(), keys...)
+}
+
+func (c *counterClient) SetBitWithContext(ctx context.Context, key string, offset int64, value int, ttl time.Duration) (int64, error) {
+	defer c.invalidate(invalidateKey + key)
+	return c.counter.SetBitWithContext(ctx, key, offset, value, ttl)
+}
+
+func (c *counterClient) SetBit(key string, offset int64, value int, ttl time.Duration) (int64, error) {
+	return c.SetBitWithContext(context.Background(), key, offset, value, ttl)
+}
+
+func (c *counterClient) GetBitWithContext(ctx context.Context, key string, offset int64) (int64, error) {
+	return c.counter.GetBitWithContext(ctx, key, offset)
+}
+
+func (c *counterClient) GetBit(key string, offset int64) (int64, error) {
+	return c.GetBitWithContext(context.Background(), key, offset)
+}
+
+func (c *counterClient) BitCountWithContext(ctx context.Context, key string) (int64, error) {
+	return c.counter.BitCountWithContext(ctx, key)
+}
+
+func (c *counterClient) BitCount(key string) (int64, error) {
+	return c.BitCountWithContext(context.Background(), key)
+}
+
+func (c *sortedSetClient) ZAddWithContext(ctx context.Context, key string, members ...redis.Z) (int64, error) {
+	defer c.invalidate(invalidateKey + key)
+	return c.sortedSet.ZAddWithContext(ctx, key, members...)
+}
+
+func (c *sortedSetClient) ZAdd(key string, members ...redis.Z) (int64, error) {
+	return c.ZAddWithContext(context.Background(), key, members...)
+}
+
+func (c *sortedSetClient) ZIncrByWithContext(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	defer c.invalidate(invalidateKey + key)
+	return c.sortedSet.ZIncrByWithContext(ctx, key, increment, member)
+}
+
+func (c *sortedSetClient) ZIncrBy(key string, increment float64, member string) (float64, error) {
+	return c.ZIncrByWithContext(context.Background(), key, increment, member)
+}
+
+func (c *sortedSetClient) ZRemWithContext(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	defer c.invalidate(invalidateKey + key)
+	return c.sortedSet.ZRemWithContext(ctx, key, members...)
+}
+
+func (c *sortedSetClient) ZRem(key string, members ...interface{}) (int64, error) {
+	return c.ZRemWithContext(context.Background(), key, members...)
+}
+
+func (c *sortedSetClient) ZRemRangeByRankWithContext(ctx context.Context, key string, start, stop int64) (int64, error) {
+	defer c.invalidate(invalidateKey + key)
+	return c.sortedSet.ZRemRangeByRankWithContext(ctx, key, start, stop)
+}
+
+func (c *sortedSetClient) ZRemRangeByRank(key string, start, stop int64) (int64, error) {
+	return c.ZRemRangeByRankWithContext(context.Background(), key, start, stop)
+}
+
+func (c *sortedSetClient) ZRangeWithContext(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	return c.sortedSet.ZRangeWithContext(ctx, key, start, stop)
+}
+
+func (c *sortedSetClient) ZRange(key string, start, stop int64) ([]redis.Z, error) {
+	return c.ZRangeWithContext(context.Background(), key, start, stop)
+}
+
+func (c *sortedSetClient) ZRevRangeWithContext(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	return c.sortedSet.ZRevRangeWithContext(ctx, key, start, stop)
+}
+
+func (c *sortedSetClient) ZRevRange(key string, start, stop int64) ([]redis.Z, error) {
+	return c.ZRevRangeWithContext(context.Background(), key, start, stop)
+}
+
+func (c *sortedSetClient) ZRangeByScoreWithContext(ctx context.Context, key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	return c.sortedSet.ZRangeByScoreWithContext(ctx, key, opt)
+}
+
+func (c *sortedSetClient) ZRangeByScore(key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	return c.ZRangeByScoreWithContext(context.Background(), key, opt)
+}
+
+func (c *sortedSetClient) ZRevRangeByScoreWithContext(ctx context.Context, key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	return c.sortedSet.ZRevRangeByScoreWithContext(ctx, key, opt)
+}
+
+func (c *sortedSetClient) ZRevRangeByScore(key string, opt redis.ZRangeBy) ([]redis.Z, error) {
+	return c.ZRevRangeByScoreWithContext(context.Background(), key, opt)
+}
+
+func (c *sortedSetClient) ZRankWithContext(ctx context.Context, key, member string) (int64, error) {
+	return c.sortedSet.ZRankWithContext(ctx, key, member)
+}
+
+func (c *sortedSetClient) ZRank(key, member string) (int64, error) {
+	return c.ZRankWithContext(context.Background(), key, member)
+}
+
+func (c *sortedSetClient) ZRevRankWithContext(ctx context.Context, key, member string) (int64, error) {
+	return c.sortedSet.ZRevRankWithContext(ctx, key, member)
+}
+
+func (c *sortedSetClient) ZRevRank(key, member string) (int64, error) {
+	return c.ZRevRankWithContext(context.Background(), key, member)
+}
+
+func (c *sortedSetClient) ZScoreWithContext(ctx context.Context, key, member string) (float64, error) {
+	return c.sortedSet.ZScoreWithContext(ctx, key, member)
+}
+
+func (c *sortedSetClient) ZScore(key, member string) (float64, error) {
+	return c.ZScoreWithContext(context.Background(), key, member)
+}
+
+func (c *sortedSetClient) ZCardWithContext(ctx context.Context, key string) (int64, error) {
+	return c.sortedSet.ZCardWithContext(ctx, key)
+}
+
+func (c *sortedSetClient) ZCard(key string) (int64, error) {
+	return c.ZCardWithContext(context.Background(), key)
+}
+
+func (c *scannerClient) ScanKeysWithContext(ctx context.Context, pattern string, count int64) ([]string, error) {
+	return c.scanner.ScanKeysWithContext(ctx, pattern, count)
+}
+
+func (c *scannerClient) ScanKeys(pattern string, count int64) ([]string, error) {
+	return c.ScanKeysWithContext(context.Background(), pattern, count)
+}
+
+func (c *scannerClient) UnlinkByPatternWithContext(ctx context.Context, pattern string, count int64) (int64, error) {
+	defer c.invalidate(invalidatePattern + pattern)
+	return c.scanner.UnlinkByPatternWithContext(ctx, pattern, count)
+}
+
+func (c *scannerClient) UnlinkByPattern(pattern string, count int64) (int64, error) {
+	return c.UnlinkByPatternWithContext(context.Background(), pattern, count)
+}
+
+func (c *pubSubClient) PublishWithContext(ctx context.Context, channel string, message interface{}) error {
+	return c.ps.PublishWithContext(ctx, channel, message)
+}
+
+func (c *pubSubClient) Publish(channel string, message interface{}) error {
+	return c.PublishWithContext(context.Background(), channel, message)
+}
+
+func (c *pubSubClient) Subscribe(channels ...string) (cache.Subscription, error) {
+	return c.ps.Subscribe(channels...)
+}
+
+func (c *pubSubClient) PSubscribe(patterns ...string) (cache.Subscription, error) {
+	return c.ps.PSubscribe(patterns...)
+}
@@ -0,0 +1,127 @@
+package tiered
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+)
+
+type (
+	entry struct {
+		key      string
+		value    *string
+		hash     map[string]string
+		zset     []redis.Z
+		expireAt time.Time
+	}
+
+	lru struct {
+		mu       sync.Mutex
+		size     int
+		ttl      time.Duration
+		ll       *list.List
+		items    map[string]*list.Element
+		revision uint64
+	}
+)
+
+func newLRU(size int, ttl time.Duration) *lru {
+	return &lru{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// get returns a copy of the live entry stored under key.
+func (l *lru) get(key string) (entry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		return entry{}, false
+	}
+
+	e := el.Value.(*entry)
+	if !time.Now().Before(e.expireAt) {
+		l.removeElement(el)
+		return entry{}, false
+	}
+
+	l.ll.MoveToFront(el)
+	return *e, true
+}
+
+// add stores e unless the layer was invalidated after revision was taken,
+// which prevents a slow remote read from caching a value that was just replaced.
+func (l *lru) add(revision uint64, e entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if revision != l.revision {
+		return
+	}
+
+	e.expireAt = time.Now().Add(l.ttl)
+	if el, ok := l.items[e.key]; ok {
+		el.Value = &e
+		l.ll.MoveToFront(el)
+		return
+	}
+
+	l.items[e.key] = l.ll.PushFront(&e)
+	for l.ll.Len() > l.size {
+		l.removeElement(l.ll.Back())
+	}
+}
+
+func (l *lru) current() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.revision
+}
+
+func (l *lru) remove(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.revision++
+	for _, key := range keys {
+		if el, ok := l.items[key]; ok {
+			l.removeElement(el)
+		}
+	}
+}
+
+func (l *lru) removeByPattern(pattern string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.revision++
+	for key, el := range l.items {
+		if cache.Match(pattern, key) {
+			l.removeElement(el)
+		}
+	}
+}
+
+func (l *lru) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.revision++
+	l.ll.Init()
+	l.items = make(map[string]*list.Element)
+}
+
+func (l *lru) removeElement(el *list.Element) {
+	l.ll.Remove(el)
+	delete(l.items, el.Value.(*entry).key)
+}
@@ -0,0 +1,93 @@
+package tiered
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+
+	"github.com/go-redis/redis"
+)
+
+type (
+	pipe struct {
+		client   *tieredClient
+		instance cache.Pipe
+		mu       sync.Mutex
+		keys     []string
+	}
+)
+
+func (p *pipe) Set(key string, value interface{}) error {
+	return p.SetWithExpiration(key, value, 0)
+}
+
+func (p *pipe) SetWithExpiration(key string, value interface{}, expired time.Duration) error {
+	p.track(key)
+	return p.instance.SetWithExpiration(key, value, expired)
+}
+
+func (p *pipe) Get(key string) cache.ValueResult {
+	return p.instance.Get(key)
+}
+
+func (p *pipe) HSet(key, field string, value interface{}) error {
+	p.track(key)
+	return p.instance.HSet(key, field, value)
+}
+
+func (p *pipe) HMSet(key string, value map[string]interface{}) error {
+	p.track(key)
+	return p.instance.HMSet(key, value)
+}
+
+func (p *pipe) HGetAll(key string) cache.StringMapResult {
+	return p.instance.HGetAll(key)
+}
+
+func (p *pipe) Del(keys ...string) cache.IntResult {
+	p.track(keys...)
+	return p.instance.Del(keys...)
+}
+
+func (p *pipe) Expire(key string, expiration time.Duration) cache.BoolResult {
+	p.track(key)
+	return p.instance.Expire(key, expiration)
+}
+
+func (p *pipe) Incr(key string) cache.IntResult {
+	p.track(key)
+	return p.instance.Incr(key)
+}
+
+func (p *pipe) ZAdd(key string, members ...redis.Z) cache.IntResult {
+	p.track(key)
+	return p.instance.ZAdd(key, members...)
+}
+
+func (p *pipe) ExecWithContext(ctx context.Context) error {
+	p.mu.Lock()
+	keys := p.keys
+	p.keys = nil
+	p.mu.Unlock()
+
+	defer func() {
+		for _, key := range keys {
+			p.client.invalidate(invalidateKey + key)
+		}
+	}()
+
+	return p.instance.ExecWithContext(ctx)
+}
+
+func (p *pipe) Exec() error {
+	return p.ExecWithContext(context.Background())
+}
+
+// track records the keys written by the pipe, their local copy is dropped once it is executed.
+func (p *pipe) track(keys ...string) {
+	p.mu.Lock()
+	p.keys = append(p.keys, keys...)
+	p.mu.Unlock()
+}
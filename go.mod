@@ -4,19 +4,25 @@ go 1.13
 
 require (
 	github.com/Shopify/sarama v1.27.0
+	github.com/alicebob/miniredis/v2 v2.30.0
 	github.com/bsm/sarama-cluster v2.1.15+incompatible
 	github.com/go-redis/redis v6.15.9+incompatible
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/gojek/heimdall v5.0.2+incompatible
 	github.com/gojektech/heimdall v5.0.2+incompatible // indirect
 	github.com/gojektech/valkyrie v0.0.0-20190210220504-8f62c1e7ba45 // indirect
+	github.com/golang/snappy v0.0.1
 	github.com/jinzhu/gorm v1.9.16
+	github.com/klauspost/compress v1.10.10
 	github.com/labstack/echo/v4 v4.1.17
 	github.com/labstack/gommon v0.3.0
 	github.com/newrelic/go-agent v3.9.0+incompatible
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.7.1
 	github.com/rifflock/lfshook v0.0.0-20180920164130-b9218ef580f5
 	github.com/segmentio/kafka-go v0.4.2
 	github.com/sirupsen/logrus v1.6.0
+	github.com/vmihailenco/msgpack/v5 v5.3.5
 	go.mongodb.org/mongo-driver v1.4.1
+	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
 )
@@ -37,13 +37,25 @@ type (
 		SetCookie(*http.Cookie)
 		Cookies() []*http.Cookie
 
+		Request() *http.Request
+		Response() http.ResponseWriter
+		RealIP() string
+
 		Bind(interface{}) error
 		Validate(interface{}) error
+
+		// Get and Set share values between the middleware and the handlers of a request.
+		Get(string) interface{}
+		Set(string, interface{})
 	}
 
 	// HandlerFunc defines a function to serve HTTP requests.
 	HandlerFunc func(RequestContext) error
 
+	// MiddlewareFunc wraps the handlers, unlike the filters given to Use it can
+	// act on the response once the next handler returned.
+	MiddlewareFunc func(next HandlerFunc) HandlerFunc
+
 	// Server ...
 	// abstract implementation of echo.Echo
 	Server struct {
@@ -133,6 +145,18 @@ func (c *context) Cookies() []*http.Cookie {
 	return c.ec.Cookies()
 }
 
+func (c *context) Request() *http.Request {
+	return c.ec.Request()
+}
+
+func (c *context) Response() http.ResponseWriter {
+	return c.ec.Response()
+}
+
+func (c *context) RealIP() string {
+	return c.ec.RealIP()
+}
+
 func (c *context) Bind(object interface{}) error {
 	return c.ec.Bind(object)
 }
@@ -141,6 +165,19 @@ func (c *context) Validate(object interface{}) error {
 	return c.ec.Validate(object)
 }
 
+func (c *context) Get(key string) interface{} {
+	return c.ec.Get(key)
+}
+
+func (c *context) Set(key string, value interface{}) {
+	c.ec.Set(key, value)
+}
+
 func (s *Server) Start(address string) error {
 	return s.echo.Start(address)
 }
+
+// ServeHTTP handles a request without listening, e.g. with net/http/httptest.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.echo.ServeHTTP(w, r)
+}
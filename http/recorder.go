@@ -0,0 +1,41 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	// Recorder keeps a copy of the response status and body while they are
+	// written to the client.
+	Recorder struct {
+		http.ResponseWriter
+		Status int
+		Body   bytes.Buffer
+	}
+)
+
+// Record tees the response written after the call into the returned
+// recorder, it returns nil when the response can't be intercepted.
+func Record(c RequestContext) *Recorder {
+	res, ok := c.Response().(*echo.Response)
+	if !ok {
+		return nil
+	}
+
+	recorder := &Recorder{ResponseWriter: res.Writer, Status: http.StatusOK}
+	res.Writer = recorder
+	return recorder
+}
+
+func (r *Recorder) WriteHeader(code int) {
+	r.Status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	r.Body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -47,6 +47,18 @@ func (s *Server) Use(handler HandlerFunc) {
 	s.echo.Use(wrapMiddleware(s, handler)[0])
 }
 
+// Wrap registers middleware around every handler of the server.
+func (s *Server) Wrap(middleware MiddlewareFunc) {
+	s.echo.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ct := &context{ec: c, server: s}
+			return middleware(func(RequestContext) error {
+				return next(c)
+			})(ct)
+		}
+	})
+}
+
 func wrapMiddleware(server *Server, handlers ...HandlerFunc) []echo.MiddlewareFunc {
 	middleware := make([]echo.MiddlewareFunc, 0)
 
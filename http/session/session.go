@@ -0,0 +1,325 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	nethttp "net/http"
+	"strings"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	"github.com/jajotz/utilities-golang/http"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultCookieName  = "session_id"
+	DefaultPrefix      = "session:"
+	DefaultIdleTimeout = 30 * time.Minute
+)
+
+const (
+	storeKey   = "session.store"
+	sessionKey = "session"
+)
+
+type (
+	Option struct {
+		// Secrets sign the session cookies, the first one signs new cookies and
+		// the others are only used to verify them while a secret is rotated.
+		Secrets    [][]byte
+		CookieName string
+		Prefix     string
+		// IdleTimeout expires the sessions left unused, every request using the
+		// session slides its expiration.
+		IdleTimeout time.Duration
+		Path        string
+		Domain      string
+		Secure      bool
+		SameSite    nethttp.SameSite
+	}
+
+	Session struct {
+		ID     string
+		Values map[string]string
+		// IsNew is set until the session is saved for the first time.
+		IsNew   bool
+		flashes []string
+	}
+
+	// Store keeps the sessions in a cache and their signed ID in a cookie, the
+	// changes made to a session are kept once it is saved.
+	Store interface {
+		// Get returns the session of the request, a new one when the cookie is
+		// missing, forged or expired.
+		Get(c http.RequestContext) (*Session, error)
+		Save(c http.RequestContext, s *Session) error
+		// Regenerate moves the session to a new ID, it should be called on login
+		// to prevent session fixation.
+		Regenerate(c http.RequestContext, s *Session) error
+		// Destroy removes the session and expires its cookie, s is reset to an
+		// empty new session under a fresh ID so saving it can't revive the old one.
+		Destroy(c http.RequestContext, s *Session) error
+	}
+
+	store struct {
+		option *Option
+		cache  cache.Cache
+	}
+
+	record struct {
+		Values  map[string]string `json:"values"`
+		Flashes []string          `json:"flashes,omitempty"`
+	}
+)
+
+func getOption(option *Option) error {
+	if len(option.Secrets) == 0 || len(option.Secrets[0]) == 0 {
+		return errors.New("session secret is required")
+	}
+
+	if option.CookieName == "" {
+		option.CookieName = DefaultCookieName
+	}
+
+	if option.Prefix == "" {
+		option.Prefix = DefaultPrefix
+	}
+
+	if option.IdleTimeout == 0 {
+		option.IdleTimeout = DefaultIdleTimeout
+	}
+
+	if option.Path == "" {
+		option.Path = "/"
+	}
+
+	return nil
+}
+
+func New(client cache.Cache, option *Option) (Store, error) {
+	if err := getOption(option); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &store{option: option, cache: client}, nil
+}
+
+func (st *store) Get(c http.RequestContext) (*Session, error) {
+	cookie, err := c.Cookie(st.option.CookieName)
+	if err != nil {
+		return st.create()
+	}
+
+	id, ok := st.verify(cookie.Value)
+	if !ok {
+		return st.create()
+	}
+
+	ctx := c.Request().Context()
+	rec := &record{}
+	if err := st.cache.GetWithContext(ctx, st.key(id), rec); err != nil {
+		if errors.Cause(err) == redis.Nil {
+			return st.create()
+		}
+		return nil, errors.Wrapf(err, "failed to get session %s!", id)
+	}
+
+	pipe := st.cache.PipelineWithContext(ctx)
+	pipe.Expire(st.key(id), st.option.IdleTimeout)
+	if err := pipe.ExecWithContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "failed to extend session %s!", id)
+	}
+	st.setCookie(c, id)
+
+	if rec.Values == nil {
+		rec.Values = make(map[string]string)
+	}
+
+	return &Session{ID: id, Values: rec.Values, flashes: rec.Flashes}, nil
+}
+
+func (st *store) Save(c http.RequestContext, s *Session) error {
+	rec := &record{Values: s.Values, Flashes: s.flashes}
+	if err := st.cache.SetWithExpirationWithContext(c.Request().Context(), st.key(s.ID), rec, st.option.IdleTimeout); err != nil {
+		return errors.Wrapf(err, "failed to save session %s!", s.ID)
+	}
+
+	s.IsNew = false
+	st.setCookie(c, s.ID)
+	return nil
+}
+
+func (st *store) Regenerate(c http.RequestContext, s *Session) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	if !s.IsNew {
+		if err := st.cache.RemoveWithContext(c.Request().Context(), st.key(s.ID)); err != nil {
+			return errors.Wrapf(err, "failed to remove session %s!", s.ID)
+		}
+	}
+
+	s.ID = id
+	return st.Save(c, s)
+}
+
+func (st *store) Destroy(c http.RequestContext, s *Session) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	if err := st.cache.RemoveWithContext(c.Request().Context(), st.key(s.ID)); err != nil {
+		return errors.Wrapf(err, "failed to remove session %s!", s.ID)
+	}
+
+	s.ID = id
+	s.Values = make(map[string]string)
+	s.flashes = nil
+	s.IsNew = true
+
+	cookie := st.cookie("")
+	cookie.MaxAge = -1
+	c.SetCookie(cookie)
+	return nil
+}
+
+// Middleware binds st to the requests for FromContext and Save.
+func Middleware(st Store) http.MiddlewareFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(c http.RequestContext) error {
+			c.Set(storeKey, st)
+			return next(c)
+		}
+	}
+}
+
+// FromContext returns the session of the request, it is loaded once from the
+// store bound by Middleware and kept on the request for the next calls.
+func FromContext(c http.RequestContext) (*Session, error) {
+	if s, ok := c.Get(sessionKey).(*Session); ok {
+		return s, nil
+	}
+
+	st, ok := c.Get(storeKey).(Store)
+	if !ok {
+		return nil, errors.New("session store is not bound to the request")
+	}
+
+	s, err := st.Get(c)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Set(sessionKey, s)
+	return s, nil
+}
+
+// Save saves the session returned by FromContext, nothing is saved when the
+// request did not load it.
+func Save(c http.RequestContext) error {
+	s, ok := c.Get(sessionKey).(*Session)
+	if !ok {
+		return nil
+	}
+
+	st, ok := c.Get(storeKey).(Store)
+	if !ok {
+		return errors.New("session store is not bound to the request")
+	}
+
+	return st.Save(c, s)
+}
+
+// AddFlash queues a message for the next request reading the flashes.
+func (s *Session) AddFlash(message string) {
+	s.flashes = append(s.flashes, message)
+}
+
+// Flashes returns and clears the queued messages, the session has to be saved
+// for them to be consumed.
+func (s *Session) Flashes() []string {
+	flashes := s.flashes
+	s.flashes = nil
+	return flashes
+}
+
+func (st *store) create() (*Session, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{ID: id, Values: make(map[string]string), IsNew: true}, nil
+}
+
+func (st *store) key(id string) string {
+	return st.option.Prefix + id
+}
+
+func (st *store) setCookie(c http.RequestContext, id string) {
+	c.SetCookie(st.cookie(id + "." + sign(st.option.Secrets[0], id)))
+}
+
+func (st *store) cookie(value string) *nethttp.Cookie {
+	return &nethttp.Cookie{
+		Name:     st.option.CookieName,
+		Value:    value,
+		Path:     st.option.Path,
+		Domain:   st.option.Domain,
+		MaxAge:   int(st.option.IdleTimeout / time.Second),
+		Secure:   st.option.Secure,
+		HttpOnly: true,
+		SameSite: st.option.SameSite,
+	}
+}
+
+// verify returns the session ID of a cookie signed by one of the secrets.
+func (st *store) verify(value string) (string, bool) {
+	i := strings.LastIndex(value, ".")
+	if i < 0 {
+		return "", false
+	}
+
+	id, signature := value[:i], value[i+1:]
+	for _, secret := range st.option.Secrets {
+		if hmac.Equal([]byte(signature), []byte(sign(secret, id))) {
+			return id, true
+		}
+	}
+
+	return "", false
+}
+
+func sign(secret []byte, id string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate session id")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (r *record) MarshalBinary() ([]byte, error) {
+	type plain record
+	return json.Marshal((*plain)(r))
+}
+
+func (r *record) UnmarshalBinary(data []byte) error {
+	type plain record
+	return json.Unmarshal(data, (*plain)(r))
+}
@@ -0,0 +1,234 @@
+package session
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache/memory"
+	"github.com/jajotz/utilities-golang/cache/redis"
+	"github.com/jajotz/utilities-golang/cache/redistest"
+	"github.com/jajotz/utilities-golang/http"
+)
+
+type testContext struct {
+	http.RequestContext
+	request *nethttp.Request
+	cookies map[string]*nethttp.Cookie
+}
+
+func newContext(cookies map[string]*nethttp.Cookie) *testContext {
+	return &testContext{request: httptest.NewRequest("GET", "/", nil), cookies: cookies}
+}
+
+func (c *testContext) Request() *nethttp.Request {
+	return c.request
+}
+
+func (c *testContext) Cookie(name string) (*nethttp.Cookie, error) {
+	if cookie, ok := c.cookies[name]; ok {
+		return cookie, nil
+	}
+	return nil, nethttp.ErrNoCookie
+}
+
+func (c *testContext) SetCookie(cookie *nethttp.Cookie) {
+	c.cookies[cookie.Name] = cookie
+}
+
+func Test_Session_round_trip(t *testing.T) {
+	st, err := New(memory.New(), &Option{Secrets: [][]byte{[]byte("secret")}})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	cookies := make(map[string]*nethttp.Cookie)
+	s, _ := st.Get(newContext(cookies))
+	if !s.IsNew {
+		t.Error("expected a new session")
+	}
+
+	s.Values["user"] = "42"
+	s.AddFlash("welcome")
+	if err := st.Save(newContext(cookies), s); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	loaded, err := st.Get(newContext(cookies))
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if loaded.IsNew || loaded.ID != s.ID || loaded.Values["user"] != "42" {
+		t.Errorf("unexpected session %+v", loaded)
+	}
+
+	if flashes := loaded.Flashes(); len(flashes) != 1 || flashes[0] != "welcome" {
+		t.Errorf("unexpected flashes %v", flashes)
+	}
+}
+
+func Test_Get_rejects_forged_cookie(t *testing.T) {
+	st, _ := New(memory.New(), &Option{Secrets: [][]byte{[]byte("secret")}})
+
+	cookies := make(map[string]*nethttp.Cookie)
+	s, _ := st.Get(newContext(cookies))
+	s.Values["user"] = "42"
+	_ = st.Save(newContext(cookies), s)
+
+	cookies[DefaultCookieName].Value = s.ID + ".forged"
+	if loaded, _ := st.Get(newContext(cookies)); !loaded.IsNew || loaded.ID == s.ID {
+		t.Error("forged cookie should start a new session")
+	}
+}
+
+func Test_Regenerate_moves_session(t *testing.T) {
+	st, _ := New(memory.New(), &Option{Secrets: [][]byte{[]byte("secret")}})
+
+	cookies := make(map[string]*nethttp.Cookie)
+	s, _ := st.Get(newContext(cookies))
+	s.Values["cart"] = "1"
+	_ = st.Save(newContext(cookies), s)
+	old := s.ID
+
+	if err := st.Regenerate(newContext(cookies), s); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if s.ID == old {
+		t.Error("expected a new session id")
+	}
+
+	loaded, _ := st.Get(newContext(cookies))
+	if loaded.ID != s.ID || loaded.Values["cart"] != "1" {
+		t.Errorf("unexpected session %+v", loaded)
+	}
+
+	if err := st.Destroy(newContext(cookies), loaded); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if cookies[DefaultCookieName].MaxAge >= 0 {
+		t.Error("destroy should expire the cookie")
+	}
+}
+
+func Test_Destroy_resets_session(t *testing.T) {
+	st, _ := New(memory.New(), &Option{Secrets: [][]byte{[]byte("secret")}})
+
+	cookies := make(map[string]*nethttp.Cookie)
+	s, _ := st.Get(newContext(cookies))
+	s.Values["user"] = "42"
+	_ = st.Save(newContext(cookies), s)
+	old := s.ID
+
+	if err := st.Destroy(newContext(cookies), s); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if s.ID == old || !s.IsNew || len(s.Values) != 0 {
+		t.Errorf("expected an empty new session, got %+v", s)
+	}
+
+	// - saving the destroyed session must not bring the old id back
+	if err := st.Save(newContext(cookies), s); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	cookies[DefaultCookieName].Value = old + "." + sign([]byte("secret"), old)
+	if loaded, _ := st.Get(newContext(cookies)); !loaded.IsNew || loaded.ID == old {
+		t.Errorf("old session should stay destroyed, got %+v", loaded)
+	}
+}
+
+func Test_Get_slides_expiration(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	client, err := redis.New(&redis.Option{Address: server.Addr()})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	st, _ := New(client, &Option{Secrets: [][]byte{[]byte("secret")}, IdleTimeout: 10 * time.Minute})
+
+	used, unused := make(map[string]*nethttp.Cookie), make(map[string]*nethttp.Cookie)
+	for _, cookies := range []map[string]*nethttp.Cookie{used, unused} {
+		s, _ := st.Get(newContext(cookies))
+		s.Values["user"] = "42"
+		if err := st.Save(newContext(cookies), s); err != nil {
+			t.Fatal("should not error ", err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		server.FastForward(6 * time.Minute)
+		if s, err := st.Get(newContext(used)); err != nil || s.IsNew || s.Values["user"] != "42" {
+			t.Fatalf("used session should outlive the idle timeout, got %+v %v", s, err)
+		}
+	}
+
+	if s, err := st.Get(newContext(unused)); err != nil || !s.IsNew {
+		t.Errorf("unused session should expire, got %+v %v", s, err)
+	}
+}
+
+func Test_FromContext_keeps_session_on_request(t *testing.T) {
+	st, _ := New(memory.New(), &Option{Secrets: [][]byte{[]byte("secret")}})
+
+	s := http.New()
+	s.Wrap(Middleware(st))
+	s.GET("/visit", func(c http.RequestContext) error {
+		first, err := FromContext(c)
+		if err != nil {
+			return err
+		}
+
+		second, err := FromContext(c)
+		if err != nil {
+			return err
+		}
+
+		if first != second {
+			t.Error("expected the session to be loaded once per request")
+		}
+
+		second.Values["visits"] += "1"
+		if err := Save(c); err != nil {
+			return err
+		}
+		return c.String(nethttp.StatusOK, first.Values["visits"])
+	})
+
+	var cookies []*nethttp.Cookie
+	for _, expected := range []string{"1", "11"} {
+		req := httptest.NewRequest(nethttp.MethodGet, "/visit", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+
+		res := httptest.NewRecorder()
+		s.ServeHTTP(res, req)
+		if res.Code != nethttp.StatusOK || res.Body.String() != expected {
+			t.Fatalf("expected %s, got %d %s", expected, res.Code, res.Body.String())
+		}
+		cookies = res.Result().Cookies()
+	}
+}
+
+func Test_FromContext_requires_middleware(t *testing.T) {
+	s := http.New()
+	s.GET("/visit", func(c http.RequestContext) error {
+		if _, err := FromContext(c); err == nil {
+			t.Error("should error without a bound store")
+		}
+
+		if err := Save(c); err != nil {
+			t.Error("should not error ", err)
+		}
+		return c.String(nethttp.StatusOK, "ok")
+	})
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(nethttp.MethodGet, "/visit", nil))
+}
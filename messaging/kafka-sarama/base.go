@@ -20,6 +20,11 @@ const (
 	DefaultProducerRetryMax     = 3
 	DefaultProducerRetryBackoff = 100
 	DefaultMaxWait              = 10 * time.Second
+	DefaultRetryMaxAttempts     = 3
+	DefaultRetryBackoff         = 100 * time.Millisecond
+	DefaultRetryMaxBackoff      = 5 * time.Second
+	DefaultRetryDelay           = 30 * time.Second
+	DefaultReplayIdle           = 5 * time.Second
 )
 
 type Kafka struct {
@@ -28,6 +33,14 @@ type Kafka struct {
 	CallbackFunctions map[string][]messaging.CallbackFunc
 	Client            sarama.Client
 	mu                *sync.Mutex
+	offsets           *offsetTracker
+	done              chan struct{}
+	stopped           chan struct{}
+	closeOnce         sync.Once
+	retryConsumer     *cluster.Consumer
+	retryStopped      chan struct{}
+	producer          sarama.SyncProducer
+	producerMu        sync.Mutex
 }
 
 type Option struct {
@@ -43,8 +56,25 @@ type Option struct {
 	ListTopics           []string
 	MaxWait              time.Duration
 	Log                  logs.Logger
+	// AtLeastOnce marks the offset of a message only after every callback of its
+	// topic succeeded, the messages are processed by ConsumerWorker workers.
+	// A message that keeps failing is retried until it succeeds, which holds its
+	// worker and the offsets of its partition, unless DeadLetter moves it aside.
+	// Otherwise the offset is marked before running the callbacks.
+	AtLeastOnce bool
+	// Retry runs the failed callbacks of a message again.
+	Retry RetryPolicy
+	// DeadLetter publishes the messages given up by Retry to the retry topics
+	// and then the dead letter topic, the message headers need a KafkaVersion of
+	// at least 0.11. The retry topics are consumed by the <ConsumerGroup>.retry
+	// group, one partition at a time so their delay only holds that partition.
+	DeadLetter messaging.DeadLetter
+	// ReplayIdle ends the replay of a partition once it had no message for it.
+	ReplayIdle time.Duration
 }
 
+type RetryPolicy = messaging.RetryPolicy
+
 func getOption(option *Option) error {
 	if option.KafkaVersion == "" {
 		return errors.New("invalid kafka version")
@@ -82,6 +112,26 @@ func getOption(option *Option) error {
 	if option.MaxWait == 0 {
 		option.MaxWait = DefaultMaxWait
 	}
+
+	if option.Retry.MaxAttempts == 0 {
+		option.Retry.MaxAttempts = DefaultRetryMaxAttempts
+	}
+
+	if option.Retry.Backoff == 0 {
+		option.Retry.Backoff = DefaultRetryBackoff
+	}
+
+	if option.Retry.MaxBackoff == 0 {
+		option.Retry.MaxBackoff = DefaultRetryMaxBackoff
+	}
+
+	if option.DeadLetter.RetryDelay == 0 {
+		option.DeadLetter.RetryDelay = DefaultRetryDelay
+	}
+
+	if option.ReplayIdle == 0 {
+		option.ReplayIdle = DefaultReplayIdle
+	}
 	return nil
 }
 
@@ -95,6 +145,8 @@ func New(option *Option) (messaging.QueueV2, error) {
 		Option:            option,
 		CallbackFunctions: make(map[string][]messaging.CallbackFunc),
 		mu:                &sync.Mutex{},
+		offsets:           newOffsetTracker(),
+		done:              make(chan struct{}),
 	}
 
 	l.Client, err = l.NewClient()
@@ -106,14 +158,25 @@ func New(option *Option) (messaging.QueueV2, error) {
 }
 
 func (l *Kafka) NewListener(option *Option) (*cluster.Consumer, error) {
+	return l.newConsumer(l.Option.ConsumerGroup, l.Option.ListTopics, cluster.ConsumerModeMultiplex)
+}
+
+func (l *Kafka) newConsumer(group string, topics []string, mode cluster.ConsumerMode) (*cluster.Consumer, error) {
+	kfkVersion, err := sarama.ParseKafkaVersion(l.Option.KafkaVersion)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
 	config := cluster.NewConfig()
+	config.Version = kfkVersion
 	config.Consumer.Return.Errors = true
 	config.Consumer.MaxWaitTime = l.Option.MaxWait
 	config.Group.Return.Notifications = true
 	config.Group.PartitionStrategy = l.Option.Strategy
 	config.Group.Heartbeat.Interval = time.Duration(l.Option.Heartbeat) * time.Second
+	config.Group.Mode = mode
 	brokers := l.Option.Host
-	return cluster.NewConsumer(brokers, l.Option.ConsumerGroup, l.Option.ListTopics, config)
+	return cluster.NewConsumer(brokers, group, topics, config)
 }
 
 func (l *Kafka) NewClient() (sarama.Client, error) {
@@ -129,16 +192,49 @@ func (l *Kafka) NewClient() (sarama.Client, error) {
 	configProducer.Producer.MaxMessageBytes = l.Option.ProducerMaxBytes
 	configProducer.Producer.Retry.Max = l.Option.ProducerRetryMax
 	configProducer.Producer.Retry.Backoff = time.Duration(l.Option.ProducerRetryBackOff) * time.Millisecond
+	configProducer.Consumer.Return.Errors = true
 	return sarama.NewClient(l.Option.Host, configProducer)
 }
 
 func (l *Kafka) Close() error {
+	// - let the workers finish their message so its offset is committed on close
+	l.closeOnce.Do(func() {
+		if l.done != nil {
+			close(l.done)
+		}
+	})
+
+	if l.stopped != nil {
+		<-l.stopped
+	}
+
+	if l.retryStopped != nil {
+		<-l.retryStopped
+	}
+
+	if l.retryConsumer != nil {
+		if err := l.retryConsumer.Close(); err != nil {
+			return errors.Wrapf(err, "Failed to Close Consumer")
+		}
+	}
+
 	if l.Consumer != nil {
 		if err := l.Consumer.Close(); err != nil {
 			return errors.Wrapf(err, "Failed to Close Consumer")
 		}
 	}
 
+	l.producerMu.Lock()
+	producer := l.producer
+	l.producer = nil
+	l.producerMu.Unlock()
+
+	if producer != nil {
+		if err := producer.Close(); err != nil {
+			return errors.Wrapf(err, "Failed to Close Producer")
+		}
+	}
+
 	if l.Client != nil {
 		if err := l.Client.Close(); err != nil {
 			return errors.Wrapf(err, "Failed to Close Producer")
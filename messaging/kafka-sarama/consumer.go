@@ -1,7 +1,12 @@
 package kafka_sarama
 
 import (
+	"sync"
+	"time"
+
 	"github.com/jajotz/utilities-golang/messaging"
+
+	"github.com/Shopify/sarama"
 )
 
 func (l *Kafka) AddTopicListener(topic string, callback messaging.CallbackFunc) {
@@ -35,25 +40,125 @@ func (l *Kafka) Listen() {
 	go func() {
 		for ntf := range l.Consumer.Notifications() {
 			l.Option.Log.Infof("Rebalanced: %+v\n", ntf)
+			if l.offsets != nil {
+				l.offsets.release(ntf.Released)
+			}
 		}
 	}()
 
+	if topics := l.retryTopics(); len(topics) > 0 && l.done != nil {
+		if err := l.listenRetries(topics); err != nil {
+			l.Option.Log.Error(err)
+		}
+	}
+
+	if l.Option.AtLeastOnce && l.done != nil {
+		l.stopped = make(chan struct{})
+		go l.consume()
+		return
+	}
+
 	go func() {
-		for {
-			select {
-			case msg, ok := <-l.Consumer.Messages():
-				if ok {
-					l.Consumer.MarkOffset(msg, "") // mark message as processed
-					for _, function := range l.CallbackFunctions[msg.Topic] {
-						err := function(msg.Value)
-						if err != nil {
-							l.Option.Log.Error(err)
-						}
-					}
-				} else {
-					l.Option.Log.Info("error")
+		for msg := range l.Consumer.Messages() {
+			l.Consumer.MarkOffset(msg, "") // mark message as processed
+			_ = l.process(msg)
+		}
+	}()
+}
+
+// consume dispatches the messages to the workers until the consumer is closed,
+// the offset of a message is marked once it and the messages received before
+// it on its partition are processed.
+func (l *Kafka) consume() {
+	defer close(l.stopped)
+
+	messages := make(chan *sarama.ConsumerMessage)
+	wg := sync.WaitGroup{}
+	for i := 0; i < l.Option.ConsumerWorker; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range messages {
+				// - a message that failed is never marked, its partition is committed
+				//   up to the message before it
+				if err := l.process(msg); err != nil {
+					continue
+				}
+
+				if offset, ok := l.offsets.done(msg.Topic, msg.Partition, msg.Offset); ok {
+					l.Consumer.MarkPartitionOffset(msg.Topic, msg.Partition, offset, "")
 				}
 			}
-		}
+		}()
+	}
+
+	defer func() {
+		close(messages)
+		wg.Wait()
 	}()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case msg, ok := <-l.Consumer.Messages():
+			if !ok {
+				return
+			}
+
+			l.offsets.start(msg.Topic, msg.Partition, msg.Offset)
+			select {
+			case messages <- msg:
+			case <-l.done:
+				return
+			}
+		}
+	}
+}
+
+// process runs the callbacks of the message topic with the retry policy, only
+// the callbacks that failed are run again. Once the attempts are exhausted the
+// message is published to the next retry or dead letter topic when enabled.
+// Otherwise in AtLeastOnce mode the retry policy starts over after MaxBackoff
+// until the callbacks succeed, and the message is given up in the other mode.
+func (l *Kafka) process(msg *sarama.ConsumerMessage) error {
+	if delay := l.Option.DeadLetter.Delay(msg.Topic); delay > 0 {
+		select {
+		case <-l.done:
+			return messaging.ErrStopped
+		case <-time.After(time.Until(msg.Timestamp.Add(delay))):
+		}
+	}
+
+	for {
+		attempts, err := l.Option.Retry.Run(l.done, msg.Value, l.callbacks(msg.Topic))
+		if err == nil || err == messaging.ErrStopped {
+			return err
+		}
+
+		if !l.Option.AtLeastOnce || l.Option.DeadLetter.Enabled {
+			l.Option.Log.Errorf("giving up message %s/%d/%d after %d attempts: %s", msg.Topic, msg.Partition, msg.Offset, attempts, err.Error())
+			return l.deadLetter(msg, attempts, err)
+		}
+
+		l.Option.Log.Errorf("message %s/%d/%d failed after %d attempts, retrying in %s: %s", msg.Topic, msg.Partition, msg.Offset, attempts, l.Option.Retry.MaxBackoff, err.Error())
+		select {
+		case <-l.done:
+			return messaging.ErrStopped
+		case <-time.After(l.Option.Retry.MaxBackoff):
+		}
+	}
+}
+
+func (l *Kafka) callbacks(topic string) []messaging.CallbackFunc {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if functions, ok := l.CallbackFunctions[topic]; ok {
+		return functions
+	}
+
+	// - retry topics run the callbacks of their source topic
+	source, _ := messaging.ParseTopic(topic)
+	return l.CallbackFunctions[source]
 }
@@ -0,0 +1,110 @@
+package kafka_sarama
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/logs"
+	"github.com/jajotz/utilities-golang/messaging"
+
+	"github.com/Shopify/sarama"
+)
+
+func Test_offsetTracker_marks_in_order(t *testing.T) {
+	tracker := newOffsetTracker()
+	for offset := int64(10); offset < 13; offset++ {
+		tracker.start("orders", 0, offset)
+	}
+
+	if _, ok := tracker.done("orders", 0, 11); ok {
+		t.Error("offset 11 should wait for offset 10")
+	}
+
+	if _, ok := tracker.done("orders", 0, 12); ok {
+		t.Error("offset 12 should wait for offset 10")
+	}
+
+	if offset, ok := tracker.done("orders", 0, 10); !ok || offset != 12 {
+		t.Errorf("expected offset 12 to be marked, got %d %v", offset, ok)
+	}
+}
+
+func Test_offsetTracker_resets_rewound_partition(t *testing.T) {
+	tracker := newOffsetTracker()
+	tracker.start("orders", 0, 10)
+	tracker.start("orders", 0, 11)
+
+	tracker.start("orders", 0, 5)
+	if offset, ok := tracker.done("orders", 0, 5); !ok || offset != 5 {
+		t.Errorf("expected offset 5 to be marked, got %d %v", offset, ok)
+	}
+}
+
+func Test_process_retries_failed_callbacks(t *testing.T) {
+	logger, _ := logs.DefaultLog()
+	l := &Kafka{
+		Option: &Option{
+			Log:   logger,
+			Retry: RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		},
+		CallbackFunctions: make(map[string][]messaging.CallbackFunc),
+		mu:                &sync.Mutex{},
+		done:              make(chan struct{}),
+	}
+
+	succeeded, failed := 0, 0
+	l.CallbackFunctions["orders"] = []messaging.CallbackFunc{
+		func([]byte) error {
+			succeeded++
+			return nil
+		},
+		func([]byte) error {
+			if failed++; failed < 3 {
+				return errors.New("temporary failure")
+			}
+			return nil
+		},
+	}
+
+	if err := l.process(&sarama.ConsumerMessage{Topic: "orders"}); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	if succeeded != 1 || failed != 3 {
+		t.Errorf("expected only the failed callback to be retried, got %d and %d calls", succeeded, failed)
+	}
+}
+
+func Test_process_keeps_failing_message_in_at_least_once_mode(t *testing.T) {
+	logger, _ := logs.DefaultLog()
+	l := &Kafka{
+		Option: &Option{
+			Log:         logger,
+			AtLeastOnce: true,
+			Retry:       RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		},
+		CallbackFunctions: make(map[string][]messaging.CallbackFunc),
+		mu:                &sync.Mutex{},
+		done:              make(chan struct{}),
+	}
+
+	calls := 0
+	l.CallbackFunctions["orders"] = []messaging.CallbackFunc{
+		func([]byte) error {
+			if calls++; calls < 5 {
+				return errors.New("temporary failure")
+			}
+			return nil
+		},
+	}
+
+	if err := l.process(&sarama.ConsumerMessage{Topic: "orders"}); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	if calls != 5 {
+		t.Errorf("expected the message to be retried until it succeeded, got %d calls", calls)
+	}
+}
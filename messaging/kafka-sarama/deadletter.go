@@ -0,0 +1,281 @@
+package kafka_sarama
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jajotz/utilities-golang/messaging"
+
+	"github.com/Shopify/sarama"
+	cluster "github.com/bsm/sarama-cluster"
+	"github.com/pkg/errors"
+)
+
+// retryTopics returns the retry topics of the topics listened to.
+func (l *Kafka) retryTopics() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	topics := make([]string, 0)
+	for topic := range l.CallbackFunctions {
+		topics = append(topics, l.Option.DeadLetter.Topics(topic)...)
+	}
+	return topics
+}
+
+// listenRetries consumes the retry topics apart from the source topics, every
+// partition is processed by its own goroutine so waiting for the delay of a
+// message only holds its partition.
+func (l *Kafka) listenRetries(topics []string) error {
+	consumer, err := l.newConsumer(l.Option.ConsumerGroup+".retry", topics, cluster.ConsumerModePartitions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to consume retry topics %v!", topics)
+	}
+
+	l.retryConsumer = consumer
+	l.retryStopped = make(chan struct{})
+
+	go func() {
+		for err := range consumer.Errors() {
+			l.Option.Log.Infof("Error: %s\n", err.Error())
+		}
+	}()
+
+	go func() {
+		for ntf := range consumer.Notifications() {
+			l.Option.Log.Infof("Rebalanced: %+v\n", ntf)
+		}
+	}()
+
+	go l.consumeRetries()
+	return nil
+}
+
+func (l *Kafka) consumeRetries() {
+	defer close(l.retryStopped)
+
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case pc, ok := <-l.retryConsumer.Partitions():
+			if !ok {
+				return
+			}
+
+			wg.Add(1)
+			go func(pc cluster.PartitionConsumer) {
+				defer wg.Done()
+				l.consumeRetryPartition(pc)
+			}(pc)
+		}
+	}
+}
+
+// consumeRetryPartition processes the messages of a retry partition in order
+// until the partition is revoked or the consumer is closed.
+func (l *Kafka) consumeRetryPartition(pc cluster.PartitionConsumer) {
+	go func() {
+		for err := range pc.Errors() {
+			l.Option.Log.Infof("Error: %s\n", err.Error())
+		}
+	}()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case msg, ok := <-pc.Messages():
+			if !ok {
+				return
+			}
+
+			if err := l.process(msg); err != nil {
+				return
+			}
+			l.retryConsumer.MarkOffset(msg, "")
+		}
+	}
+}
+
+// deadLetter publishes a given up message to its next topic, the publication is
+// retried until it succeeds or the consumer is closed so the message is not
+// lost once its offset is marked.
+func (l *Kafka) deadLetter(msg *sarama.ConsumerMessage, attempts int, cause error) error {
+	topic, headers := l.Option.DeadLetter.Route(&messaging.Failure{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Headers:   fromRecordHeaders(msg.Headers),
+		Attempts:  attempts,
+		Err:       cause,
+	})
+	if topic == "" {
+		return cause
+	}
+
+	for {
+		err := l.send(&sarama.ProducerMessage{
+			Topic:   topic,
+			Key:     byteEncoder(msg.Key),
+			Value:   sarama.ByteEncoder(msg.Value),
+			Headers: toRecordHeaders(headers),
+		})
+		if err == nil {
+			return nil
+		}
+
+		l.Option.Log.Error(errors.Wrapf(err, "failed to publish message %s/%d/%d to %s!", msg.Topic, msg.Partition, msg.Offset, topic))
+		select {
+		case <-l.done:
+			return messaging.ErrStopped
+		case <-time.After(l.Option.Retry.MaxBackoff):
+		}
+	}
+}
+
+// send publishes msg with a producer created on first use and closed with the
+// client.
+func (l *Kafka) send(msg *sarama.ProducerMessage) error {
+	l.producerMu.Lock()
+	if l.producer == nil {
+		producer, err := sarama.NewSyncProducerFromClient(l.Client)
+		if err != nil {
+			l.producerMu.Unlock()
+			return errors.WithStack(err)
+		}
+		l.producer = producer
+	}
+	producer := l.producer
+	l.producerMu.Unlock()
+
+	_, _, err := producer.SendMessage(msg)
+	return err
+}
+
+func (l *Kafka) ReplayWithContext(ctx context.Context, topic string, limit int) (int, error) {
+	dlq := messaging.DeadLetterTopic(topic)
+	partitions, err := l.Client.Partitions(dlq)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get partitions of %s!", dlq)
+	}
+
+	offsets, err := sarama.NewOffsetManagerFromClient(l.Option.ConsumerGroup+".replay", l.Client)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer func() { _ = offsets.Close() }()
+
+	consumer, err := sarama.NewConsumerFromClient(l.Client)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer func() { _ = consumer.Close() }()
+
+	replayed := 0
+	for _, partition := range partitions {
+		if limit > 0 && replayed >= limit {
+			break
+		}
+
+		n, err := l.replayPartition(ctx, consumer, offsets, dlq, partition, limit-replayed)
+		replayed += n
+		if err != nil {
+			return replayed, err
+		}
+	}
+
+	return replayed, nil
+}
+
+func (l *Kafka) Replay(topic string, limit int) (int, error) {
+	return l.ReplayWithContext(context.Background(), topic, limit)
+}
+
+// replayPartition republishes the messages of a dead letter partition from the
+// offset committed by the previous replay up to the current end of partition,
+// or until no message came for ReplayIdle when offsets are missing from it.
+func (l *Kafka) replayPartition(ctx context.Context, consumer sarama.Consumer, offsets sarama.OffsetManager, topic string, partition int32, limit int) (int, error) {
+	pom, err := offsets.ManagePartition(topic, partition)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer func() { _ = pom.Close() }()
+
+	next, _ := pom.NextOffset()
+	if next < 0 {
+		if next, err = l.Client.GetOffset(topic, partition, sarama.OffsetOldest); err != nil {
+			return 0, errors.Wrapf(err, "failed to get offset of %s/%d!", topic, partition)
+		}
+	}
+
+	end, err := l.Client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get offset of %s/%d!", topic, partition)
+	}
+
+	if next >= end {
+		return 0, nil
+	}
+
+	pc, err := consumer.ConsumePartition(topic, partition, next)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to consume %s/%d!", topic, partition)
+	}
+	defer func() { _ = pc.Close() }()
+
+	replayed := 0
+	for next < end && (limit <= 0 || replayed < limit) {
+		select {
+		case <-ctx.Done():
+			return replayed, ctx.Err()
+		case <-time.After(l.Option.ReplayIdle):
+			return replayed, nil
+		case err := <-pc.Errors():
+			return replayed, errors.Wrapf(err, "failed to consume %s/%d!", topic, partition)
+		case msg := <-pc.Messages():
+			target, headers := messaging.Replay(msg.Topic, fromRecordHeaders(msg.Headers))
+			if err := l.send(&sarama.ProducerMessage{
+				Topic:   target,
+				Key:     byteEncoder(msg.Key),
+				Value:   sarama.ByteEncoder(msg.Value),
+				Headers: toRecordHeaders(headers),
+			}); err != nil {
+				return replayed, errors.Wrapf(err, "failed to replay message %s/%d/%d!", topic, partition, msg.Offset)
+			}
+
+			next = msg.Offset + 1
+			pom.MarkOffset(next, "")
+			replayed++
+		}
+	}
+
+	return replayed, nil
+}
+
+func fromRecordHeaders(headers []*sarama.RecordHeader) map[string]string {
+	m := make(map[string]string, len(headers))
+	for _, h := range headers {
+		m[string(h.Key)] = string(h.Value)
+	}
+	return m
+}
+
+func toRecordHeaders(headers map[string]string) []sarama.RecordHeader {
+	records := make([]sarama.RecordHeader, 0, len(headers))
+	for k, v := range headers {
+		records = append(records, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+	return records
+}
+
+func byteEncoder(b []byte) sarama.Encoder {
+	if b == nil {
+		return nil
+	}
+	return sarama.ByteEncoder(b)
+}
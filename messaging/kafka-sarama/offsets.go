@@ -0,0 +1,81 @@
+package kafka_sarama
+
+import (
+	"sync"
+)
+
+type (
+	topicPartition struct {
+		topic     string
+		partition int32
+	}
+
+	// partitionOffsets holds the offsets in flight of a partition in the order
+	// they were received.
+	partitionOffsets struct {
+		pending []int64
+		done    map[int64]bool
+	}
+
+	// offsetTracker tells which offset can be marked once messages of the same
+	// partition complete out of order, an offset is only marked after every
+	// offset received before it completed.
+	offsetTracker struct {
+		mu         sync.Mutex
+		partitions map[topicPartition]*partitionOffsets
+	}
+)
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[topicPartition]*partitionOffsets)}
+}
+
+// start records offset as in flight.
+func (t *offsetTracker) start(topic string, partition int32, offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := topicPartition{topic: topic, partition: partition}
+	p, ok := t.partitions[key]
+
+	// - the partition was consumed again from an older offset after a rebalance
+	if !ok || (len(p.pending) > 0 && offset <= p.pending[len(p.pending)-1]) {
+		p = &partitionOffsets{done: make(map[int64]bool)}
+		t.partitions[key] = p
+	}
+
+	p.pending = append(p.pending, offset)
+}
+
+// done records offset as processed and returns the highest offset that can be
+// marked, ok is false while an older offset of the partition is in flight.
+func (t *offsetTracker) done(topic string, partition int32, offset int64) (mark int64, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, found := t.partitions[topicPartition{topic: topic, partition: partition}]
+	if !found || len(p.pending) == 0 || offset < p.pending[0] {
+		return 0, false
+	}
+
+	p.done[offset] = true
+	for len(p.pending) > 0 && p.done[p.pending[0]] {
+		mark, ok = p.pending[0], true
+		delete(p.done, mark)
+		p.pending = p.pending[1:]
+	}
+
+	return mark, ok
+}
+
+// release forgets the partitions revoked from the consumer.
+func (t *offsetTracker) release(partitions map[string][]int32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for topic, ids := range partitions {
+		for _, partition := range ids {
+			delete(t.partitions, topicPartition{topic: topic, partition: partition})
+		}
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 	"sync"
 	"time"
-	"github.com/jajotz//logs"
+
+	"github.com/jajotz/utilities-golang/logs"
 	"github.com/jajotz/utilities-golang/messaging"
 
 	"github.com/pkg/errors"
@@ -20,9 +21,23 @@ type (
 	kafka       struct {
 		option  Option
 		log     logs.Logger
-		writers map[string]*kfk.Writer
-		readers map[string]*kfk.Reader
+		writers map[string]writer
+		readers map[string]reader
 		mu      sync.Mutex
+		// newReader and newWriter open the kafka-go readers and writers.
+		newReader func(config kfk.ReaderConfig) reader
+		newWriter func(config kfk.WriterConfig) writer
+	}
+
+	reader interface {
+		FetchMessage(ctx context.Context) (kfk.Message, error)
+		CommitMessages(ctx context.Context, msgs ...kfk.Message) error
+		Close() error
+	}
+
+	writer interface {
+		WriteMessages(ctx context.Context, msgs ...kfk.Message) error
+		Close() error
 	}
 )
 
@@ -44,6 +59,14 @@ type Option struct {
 	ReadBackoffMax    time.Duration
 	CommitInterval    time.Duration
 	CompressionCodec  Compression
+	// Retry runs the failed callbacks of a message again, the offset of a message
+	// is committed once it succeeded or was given up.
+	Retry messaging.RetryPolicy
+	// DeadLetter publishes the messages given up by Retry to the retry topics
+	// and then the dead letter topic.
+	DeadLetter messaging.DeadLetter
+	// ReplayIdle ends a replay once the dead letter topic had no message for it.
+	ReplayIdle time.Duration
 }
 
 func getOption(option *Option) error {
@@ -72,6 +95,22 @@ func getOption(option *Option) error {
 		option.ReadBackoffMax = 1 * time.Second
 	}
 
+	if option.Retry.MaxAttempts == 0 {
+		option.Retry.MaxAttempts = 3
+	}
+	if option.Retry.Backoff == 0 {
+		option.Retry.Backoff = 100 * time.Millisecond
+	}
+	if option.Retry.MaxBackoff == 0 {
+		option.Retry.MaxBackoff = 5 * time.Second
+	}
+	if option.DeadLetter.RetryDelay == 0 {
+		option.DeadLetter.RetryDelay = 30 * time.Second
+	}
+	if option.ReplayIdle == 0 {
+		option.ReplayIdle = 5 * time.Second
+	}
+
 	if option.CompressionCodec == "" {
 		option.CompressionCodec = Snappy
 	}
@@ -90,9 +129,15 @@ func New(option Option, log logs.Logger) (messaging.Queue, error) {
 	return &kafka{
 		option:  option,
 		log:     log,
-		writers: make(map[string]*kfk.Writer),
-		readers: make(map[string]*kfk.Reader),
+		writers: make(map[string]writer),
+		readers: make(map[string]reader),
 		mu:      sync.Mutex{},
+		newReader: func(config kfk.ReaderConfig) reader {
+			return kfk.NewReader(config)
+		},
+		newWriter: func(config kfk.WriterConfig) writer {
+			return kfk.NewWriter(config)
+		},
 	}, nil
 }
 
@@ -100,43 +145,90 @@ func (k *kafka) Ping() error {
 	return nil
 }
 
+// ReadWithContext consumes topic and its retry topics until ctx is done, every
+// retry topic has its own reader so waiting for the delay of its messages does
+// not hold topic.
 func (k *kafka) ReadWithContext(ctx context.Context, topic string, callbacks []messaging.CallbackFunc) error {
 	if len(callbacks) < 1 {
 		return errors.New("At least 1 callbacks is required")
 	}
 
-	k.mu.Lock()
-	if _, ok := k.readers[topic]; !ok {
-		reader := kfk.NewReader(kfk.ReaderConfig{
-			Brokers:           k.option.Host,
-			GroupID:           k.option.ConsumerGroup,
-			Topic:             topic,
-			MaxWait:           time.Duration(k.option.Interval) * time.Millisecond,
-			QueueCapacity:     k.option.QueueCapacity,
-			HeartbeatInterval: k.option.HeartbeatInterval,
-			ReadBackoffMin:    k.option.ReadBackoffMin,
-			ReadBackoffMax:    k.option.ReadBackoffMax,
-			CommitInterval:    k.option.CommitInterval,
-			MinBytes:          k.option.MinBytes,
-			MaxBytes:          k.option.MaxBytes,
-		})
-		k.readers[topic] = reader
+	for _, t := range k.option.DeadLetter.Topics(topic) {
+		go func(t string) {
+			_ = k.consume(ctx, t, callbacks)
+		}(t)
 	}
-	k.mu.Unlock()
 
-	reader := k.readers[topic]
+	return k.consume(ctx, topic, callbacks)
+}
 
+func (k *kafka) consume(ctx context.Context, topic string, callbacks []messaging.CallbackFunc) error {
+	reader := k.reader(topic)
 	for {
-		m, err := reader.ReadMessage(ctx)
+		m, err := reader.FetchMessage(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			k.log.Error(err)
 			continue
 		}
 
-		for _, c := range callbacks {
-			if err = c(m.Value); err != nil {
-				k.log.Error(err)
-			}
+		if err := k.process(ctx, m, callbacks); err != nil {
+			return err
+		}
+
+		if err := reader.CommitMessages(ctx, m); err != nil {
+			k.log.Error(err)
+		}
+	}
+}
+
+// process runs the callbacks with the retry policy, the message is published to
+// the next retry or dead letter topic once the attempts are exhausted. It only
+// fails when ctx is done, leaving the message uncommitted.
+func (k *kafka) process(ctx context.Context, m kfk.Message, callbacks []messaging.CallbackFunc) error {
+	if delay := k.option.DeadLetter.Delay(m.Topic); delay > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(m.Time.Add(delay))):
+		}
+	}
+
+	attempts, err := k.option.Retry.Run(ctx.Done(), m.Value, callbacks)
+	if err == nil {
+		return nil
+	}
+
+	if err == messaging.ErrStopped {
+		return ctx.Err()
+	}
+
+	k.log.Errorf("giving up message %s/%d/%d after %d attempts: %s", m.Topic, m.Partition, m.Offset, attempts, err.Error())
+	topic, headers := k.option.DeadLetter.Route(&messaging.Failure{
+		Topic:     m.Topic,
+		Partition: int32(m.Partition),
+		Offset:    m.Offset,
+		Headers:   fromHeaders(m.Headers),
+		Attempts:  attempts,
+		Err:       err,
+	})
+	if topic == "" {
+		return nil
+	}
+
+	for {
+		err := k.write(ctx, topic, kfk.Message{Key: m.Key, Value: m.Value, Headers: toHeaders(headers)})
+		if err == nil {
+			return nil
+		}
+
+		k.log.Error(err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(k.option.Retry.MaxBackoff):
 		}
 	}
 }
@@ -146,6 +238,106 @@ func (k *kafka) Read(topic string, callbacks []messaging.CallbackFunc) error {
 }
 
 func (k *kafka) PublishWithContext(ctx context.Context, topic, message string) error {
+	if err := k.write(ctx, topic, kfk.Message{Value: []byte(message)}); err != nil {
+		k.log.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (k *kafka) Publish(topic, message string) error {
+	return k.PublishWithContext(context.Background(), topic, message)
+}
+
+func (k *kafka) ReplayWithContext(ctx context.Context, topic string, limit int) (int, error) {
+	dlq := messaging.DeadLetterTopic(topic)
+	reader := k.newReader(kfk.ReaderConfig{
+		Brokers:        k.option.Host,
+		GroupID:        k.option.ConsumerGroup + ".replay",
+		Topic:          dlq,
+		MaxWait:        time.Duration(k.option.Interval) * time.Millisecond,
+		ReadBackoffMin: k.option.ReadBackoffMin,
+		ReadBackoffMax: k.option.ReadBackoffMax,
+		MinBytes:       k.option.MinBytes,
+		MaxBytes:       k.option.MaxBytes,
+	})
+	defer func() { _ = reader.Close() }()
+
+	replayed := 0
+	for limit <= 0 || replayed < limit {
+		fetchCtx, cancel := context.WithTimeout(ctx, k.option.ReplayIdle)
+		m, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && fetchCtx.Err() == context.DeadlineExceeded {
+				return replayed, nil
+			}
+			return replayed, errors.Wrapf(err, "failed to read dead letter topic %s!", dlq)
+		}
+
+		target, headers := messaging.Replay(m.Topic, fromHeaders(m.Headers))
+		if err := k.write(ctx, target, kfk.Message{Key: m.Key, Value: m.Value, Headers: toHeaders(headers)}); err != nil {
+			return replayed, errors.Wrapf(err, "failed to replay message %s/%d/%d!", m.Topic, m.Partition, m.Offset)
+		}
+
+		if err := reader.CommitMessages(ctx, m); err != nil {
+			return replayed, errors.Wrapf(err, "failed to commit dead letter %s/%d/%d!", m.Topic, m.Partition, m.Offset)
+		}
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+func (k *kafka) Replay(topic string, limit int) (int, error) {
+	return k.ReplayWithContext(context.Background(), topic, limit)
+}
+
+func (k *kafka) Close() error {
+	var err error
+	// - close writer
+	for _, w := range k.writers {
+		if e := w.Close(); e != nil {
+			err = e
+			k.log.Error(err)
+		}
+	}
+
+	// - close reader
+	for _, r := range k.readers {
+		if e := r.Close(); e != nil {
+			err = e
+			k.log.Error(err)
+		}
+	}
+
+	return err
+}
+
+func (k *kafka) reader(topic string) reader {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.readers[topic]; !ok {
+		k.readers[topic] = k.newReader(kfk.ReaderConfig{
+			Brokers:           k.option.Host,
+			GroupID:           k.option.ConsumerGroup,
+			Topic:             topic,
+			MaxWait:           time.Duration(k.option.Interval) * time.Millisecond,
+			QueueCapacity:     k.option.QueueCapacity,
+			HeartbeatInterval: k.option.HeartbeatInterval,
+			ReadBackoffMin:    k.option.ReadBackoffMin,
+			ReadBackoffMax:    k.option.ReadBackoffMax,
+			CommitInterval:    k.option.CommitInterval,
+			MinBytes:          k.option.MinBytes,
+			MaxBytes:          k.option.MaxBytes,
+		})
+	}
+
+	return k.readers[topic]
+}
+
+func (k *kafka) write(ctx context.Context, topic string, msg kfk.Message) error {
 	k.mu.Lock()
 
 	var compressionCodec kfk.CompressionCodec
@@ -159,7 +351,7 @@ func (k *kafka) PublishWithContext(ctx context.Context, topic, message string) e
 	}
 
 	if _, ok := k.writers[topic]; !ok {
-		writer := kfk.NewWriter(kfk.WriterConfig{
+		k.writers[topic] = k.newWriter(kfk.WriterConfig{
 			Brokers:          k.option.Host,
 			Topic:            topic,
 			Balancer:         &kfk.Hash{},
@@ -167,39 +359,28 @@ func (k *kafka) PublishWithContext(ctx context.Context, topic, message string) e
 			BatchTimeout:     time.Duration(k.option.Interval) * time.Millisecond,
 			CompressionCodec: compressionCodec,
 		})
-		k.writers[topic] = writer
 	}
+	w := k.writers[topic]
 	k.mu.Unlock()
 
-	w := k.writers[topic]
-	if err := w.WriteMessages(context.Background(), kfk.Message{Value: []byte(message)}); err != nil {
-		k.log.Error(err)
+	if err := w.WriteMessages(ctx, msg); err != nil {
 		return errors.Wrapf(err, "failed to publish message on topic %s", topic)
 	}
 	return nil
 }
 
-func (k *kafka) Publish(topic, message string) error {
-	return k.PublishWithContext(context.Background(), topic, message)
-}
-
-func (k *kafka) Close() error {
-	var err error
-	// - close writer
-	for _, w := range k.writers {
-		if e := w.Close(); e != nil {
-			err = e
-			k.log.Error(err)
-		}
+func fromHeaders(headers []kfk.Header) map[string]string {
+	m := make(map[string]string, len(headers))
+	for _, h := range headers {
+		m[h.Key] = string(h.Value)
 	}
+	return m
+}
 
-	// - close reader
-	for _, r := range k.readers {
-		if e := r.Close(); e != nil {
-			err = e
-			k.log.Error(err)
-		}
+func toHeaders(headers map[string]string) []kfk.Header {
+	kh := make([]kfk.Header, 0, len(headers))
+	for k, v := range headers {
+		kh = append(kh, kfk.Header{Key: k, Value: []byte(v)})
 	}
-
-	return err
+	return kh
 }
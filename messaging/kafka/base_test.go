@@ -0,0 +1,255 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jajotz/utilities-golang/logs"
+	"github.com/jajotz/utilities-golang/messaging"
+
+	kfk "github.com/segmentio/kafka-go"
+)
+
+type (
+	testWriter struct {
+		mu       sync.Mutex
+		messages []kfk.Message
+	}
+
+	testReader struct {
+		messages  chan kfk.Message
+		committed []kfk.Message
+	}
+)
+
+func (w *testWriter) WriteMessages(ctx context.Context, msgs ...kfk.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *testWriter) Close() error {
+	return nil
+}
+
+func (r *testReader) FetchMessage(ctx context.Context) (kfk.Message, error) {
+	select {
+	case <-ctx.Done():
+		return kfk.Message{}, ctx.Err()
+	case m := <-r.messages:
+		return m, nil
+	}
+}
+
+func (r *testReader) CommitMessages(ctx context.Context, msgs ...kfk.Message) error {
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *testReader) Close() error {
+	return nil
+}
+
+func newTestKafka(t *testing.T, option Option, readers map[string]*testReader) (*kafka, map[string]*testWriter) {
+	logger, _ := logs.DefaultLog()
+	option.Host = []string{"localhost:9092"}
+	option.ConsumerGroup = "test"
+
+	q, err := New(option, logger)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	k := q.(*kafka)
+	writers := make(map[string]*testWriter)
+	k.newWriter = func(config kfk.WriterConfig) writer {
+		writers[config.Topic] = &testWriter{}
+		return writers[config.Topic]
+	}
+	k.newReader = func(config kfk.ReaderConfig) reader {
+		return readers[config.Topic]
+	}
+
+	return k, writers
+}
+
+func header(headers []kfk.Header, key string) string {
+	return fromHeaders(headers)[key]
+}
+
+func Test_process_routes_failed_message_to_retry_topic(t *testing.T) {
+	k, writers := newTestKafka(t, Option{
+		Retry:      messaging.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+		DeadLetter: messaging.DeadLetter{Enabled: true, RetryTopics: 1},
+	}, nil)
+
+	calls := 0
+	callbacks := []messaging.CallbackFunc{func([]byte) error {
+		calls++
+		return errors.New("temporary failure")
+	}}
+
+	m := kfk.Message{Topic: "orders", Partition: 1, Offset: 7, Key: []byte("order-1"), Value: []byte("value"),
+		Headers: []kfk.Header{{Key: "trace", Value: []byte("abc")}}}
+	if err := k.process(context.Background(), m, callbacks); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected 2 attempts, got %d", calls)
+	}
+
+	w, ok := writers["orders.retry.1"]
+	if !ok || len(w.messages) != 1 {
+		t.Fatalf("expected the message on the retry topic, got %+v", writers)
+	}
+
+	routed := w.messages[0]
+	if string(routed.Key) != "order-1" || string(routed.Value) != "value" {
+		t.Errorf("unexpected routed message %+v", routed)
+	}
+
+	expected := map[string]string{
+		messaging.HeaderOriginalTopic:     "orders",
+		messaging.HeaderOriginalPartition: "1",
+		messaging.HeaderOriginalOffset:    "7",
+		messaging.HeaderAttempt:           "2",
+		messaging.HeaderError:             "temporary failure",
+		"trace":                           "abc",
+	}
+	for key, value := range expected {
+		if got := header(routed.Headers, key); got != value {
+			t.Errorf("expected header %s %s, got %s", key, value, got)
+		}
+	}
+}
+
+func Test_process_routes_last_retry_to_dead_letter(t *testing.T) {
+	k, writers := newTestKafka(t, Option{
+		Retry:      messaging.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+		DeadLetter: messaging.DeadLetter{Enabled: true, RetryTopics: 1, RetryDelay: time.Minute},
+	}, nil)
+
+	callbacks := []messaging.CallbackFunc{func([]byte) error {
+		return errors.New("still failing")
+	}}
+
+	// - the message waited its delay on the retry topic already
+	m := kfk.Message{Topic: "orders.retry.1", Partition: 0, Offset: 3, Value: []byte("value"), Time: time.Now().Add(-time.Minute),
+		Headers: toHeaders(map[string]string{
+			messaging.HeaderOriginalTopic:     "orders",
+			messaging.HeaderOriginalPartition: "1",
+			messaging.HeaderOriginalOffset:    "7",
+			messaging.HeaderAttempt:           "2",
+		})}
+	if err := k.process(context.Background(), m, callbacks); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	w, ok := writers["orders.dlq"]
+	if !ok || len(w.messages) != 1 {
+		t.Fatalf("expected the message on the dead letter topic, got %+v", writers)
+	}
+
+	headers := w.messages[0].Headers
+	if header(headers, messaging.HeaderOriginalTopic) != "orders" || header(headers, messaging.HeaderOriginalOffset) != "7" {
+		t.Errorf("expected the origin to be kept, got %+v", fromHeaders(headers))
+	}
+
+	if header(headers, messaging.HeaderAttempt) != "4" || header(headers, messaging.HeaderError) != "still failing" {
+		t.Errorf("unexpected failure headers %+v", fromHeaders(headers))
+	}
+}
+
+func Test_process_gives_up_without_dead_letter(t *testing.T) {
+	k, writers := newTestKafka(t, Option{
+		Retry: messaging.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+	}, nil)
+
+	callbacks := []messaging.CallbackFunc{func([]byte) error {
+		return errors.New("failure")
+	}}
+
+	if err := k.process(context.Background(), kfk.Message{Topic: "orders"}, callbacks); err != nil {
+		t.Error("should not error ", err)
+	}
+
+	if len(writers) != 0 {
+		t.Errorf("expected no message to be published, got %+v", writers)
+	}
+}
+
+func Test_process_stops_with_context(t *testing.T) {
+	k, writers := newTestKafka(t, Option{
+		Retry:      messaging.RetryPolicy{MaxAttempts: 3, Backoff: time.Minute},
+		DeadLetter: messaging.DeadLetter{Enabled: true},
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	callbacks := []messaging.CallbackFunc{func([]byte) error {
+		cancel()
+		return errors.New("failure")
+	}}
+
+	if err := k.process(ctx, kfk.Message{Topic: "orders"}, callbacks); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	if len(writers) != 0 {
+		t.Errorf("expected the message to be left uncommitted, got %+v", writers)
+	}
+}
+
+func Test_ReplayWithContext_republishes_dead_letters(t *testing.T) {
+	dlq := &testReader{messages: make(chan kfk.Message, 2)}
+	k, writers := newTestKafka(t, Option{ReplayIdle: 10 * time.Millisecond}, map[string]*testReader{"orders.dlq": dlq})
+
+	for _, offset := range []int64{1, 2} {
+		dlq.messages <- kfk.Message{Topic: "orders.dlq", Offset: offset, Value: []byte("value"),
+			Headers: toHeaders(map[string]string{
+				messaging.HeaderOriginalTopic: "orders",
+				messaging.HeaderAttempt:       "3",
+				messaging.HeaderError:         "failure",
+				"trace":                       "abc",
+			})}
+	}
+
+	replayed, err := k.ReplayWithContext(context.Background(), "orders", 0)
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if replayed != 2 || len(dlq.committed) != 2 {
+		t.Errorf("expected 2 replayed and committed messages, got %d %d", replayed, len(dlq.committed))
+	}
+
+	w, ok := writers["orders"]
+	if !ok || len(w.messages) != 2 {
+		t.Fatalf("expected the messages on the source topic, got %+v", writers)
+	}
+
+	headers := fromHeaders(w.messages[0].Headers)
+	if len(headers) != 1 || headers["trace"] != "abc" {
+		t.Errorf("expected the failure headers to be stripped, got %+v", headers)
+	}
+}
+
+func Test_ReplayWithContext_stops_at_limit(t *testing.T) {
+	dlq := &testReader{messages: make(chan kfk.Message, 2)}
+	k, writers := newTestKafka(t, Option{ReplayIdle: 10 * time.Millisecond}, map[string]*testReader{"orders.dlq": dlq})
+
+	dlq.messages <- kfk.Message{Topic: "orders.dlq", Offset: 1}
+	dlq.messages <- kfk.Message{Topic: "orders.dlq", Offset: 2}
+
+	if replayed, err := k.ReplayWithContext(context.Background(), "orders", 1); err != nil || replayed != 1 {
+		t.Errorf("expected 1 replayed message, got %d %v", replayed, err)
+	}
+
+	if len(writers["orders"].messages) != 1 || len(dlq.committed) != 1 {
+		t.Errorf("expected a single message to be replayed, got %d", len(writers["orders"].messages))
+	}
+}
@@ -0,0 +1,437 @@
+package redisstream
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jajotz/utilities-golang/cache"
+	cacheredis "github.com/jajotz/utilities-golang/cache/redis"
+	"github.com/jajotz/utilities-golang/logs"
+	"github.com/jajotz/utilities-golang/messaging"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultBatchSize     = 10
+	DefaultBlock         = 5 * time.Second
+	DefaultMaxLen        = 100000
+	DefaultClaimMinIdle  = time.Minute
+	DefaultClaimInterval = 30 * time.Second
+	DefaultMaxDeliveries = 10
+
+	// Field is the stream entry field holding the published message.
+	Field = "message"
+)
+
+type (
+	Option struct {
+		// Redis holds the connection options shared with the redis cache.
+		Redis         *cacheredis.Option
+		ConsumerGroup string
+		// Consumer names this process inside the group, defaults to hostname-pid.
+		Consumer  string
+		BatchSize int64
+		Block     time.Duration
+		// MaxLen caps the stream length on publish, older entries are trimmed
+		// approximately. A negative value disables trimming.
+		MaxLen int64
+		// ClaimMinIdle is how long an entry stays unacknowledged before another
+		// consumer takes it over, e.g. after a crash or a failed callback.
+		ClaimMinIdle  time.Duration
+		ClaimInterval time.Duration
+		// MaxDeliveries is the number of times an entry is delivered before it is
+		// given up and acknowledged. A negative value redelivers it forever.
+		MaxDeliveries int64
+		// DeadLetter publishes the entries given up to the dead letter stream of
+		// their topic, see messaging.DeadLetterTopic.
+		DeadLetter bool
+		Log        logs.Logger
+	}
+
+	stream struct {
+		option    *Option
+		client    cache.Cache
+		r         redis.UniversalClient
+		mu        sync.Mutex
+		groups    map[string]bool
+		callbacks map[string][]messaging.CallbackFunc
+		ctx       context.Context
+		cancel    context.CancelFunc
+		wg        sync.WaitGroup
+	}
+)
+
+func getOption(option *Option) error {
+	if option.Redis == nil {
+		return errors.New("Redis option is required!")
+	}
+
+	if option.ConsumerGroup == "" {
+		return errors.New("ConsumerGroup is required!")
+	}
+
+	if option.Consumer == "" {
+		hostname, _ := os.Hostname()
+		option.Consumer = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	if option.BatchSize == 0 {
+		option.BatchSize = DefaultBatchSize
+	}
+
+	if option.Block == 0 {
+		option.Block = DefaultBlock
+	}
+
+	if option.MaxLen == 0 {
+		option.MaxLen = DefaultMaxLen
+	}
+
+	if option.ClaimMinIdle == 0 {
+		option.ClaimMinIdle = DefaultClaimMinIdle
+	}
+
+	if option.ClaimInterval == 0 {
+		option.ClaimInterval = DefaultClaimInterval
+	}
+
+	if option.MaxDeliveries == 0 {
+		option.MaxDeliveries = DefaultMaxDeliveries
+	}
+
+	if option.Log == nil {
+		logger, _ := logs.DefaultLog()
+		option.Log = logger
+	}
+
+	return nil
+}
+
+// New creates a messaging.Queue backed by redis streams, every topic is a
+// stream consumed through the consumer group of the option.
+func New(option *Option) (messaging.Queue, error) {
+	return newStream(option)
+}
+
+// NewV2 creates a messaging.QueueV2 backed by redis streams.
+func NewV2(option *Option) (messaging.QueueV2, error) {
+	return newStream(option)
+}
+
+func newStream(option *Option) (*stream, error) {
+	if err := getOption(option); err != nil {
+		return nil, errors.Wrap(err, "Failed to Initialize Redis Stream")
+	}
+
+	client, err := cacheredis.New(option.Redis)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &stream{
+		option:    option,
+		client:    client,
+		r:         client.(cache.Instance).Instance(),
+		groups:    make(map[string]bool),
+		callbacks: make(map[string][]messaging.CallbackFunc),
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+func (s *stream) Ping() error {
+	return s.client.Ping()
+}
+
+// ReadWithContext consumes topic until ctx is done. An entry is acknowledged
+// once every callback succeeded, otherwise it stays pending and is delivered
+// again after ClaimMinIdle, up to MaxDeliveries times.
+func (s *stream) ReadWithContext(ctx context.Context, topic string, callbacks []messaging.CallbackFunc) error {
+	if len(callbacks) < 1 {
+		return errors.New("At least 1 callbacks is required")
+	}
+
+	if err := s.createGroup(topic); err != nil {
+		return err
+	}
+
+	var claimedAt time.Time
+	for {
+		if err := ctx.Err(); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if time.Since(claimedAt) >= s.option.ClaimInterval {
+			claimedAt = time.Now()
+			s.claim(topic, callbacks)
+		}
+
+		streams, err := s.r.XReadGroup(&redis.XReadGroupArgs{
+			Group:    s.option.ConsumerGroup,
+			Consumer: s.option.Consumer,
+			Streams:  []string{topic, ">"},
+			Count:    s.option.BatchSize,
+			Block:    s.option.Block,
+		}).Result()
+
+		if err == redis.Nil {
+			continue
+		}
+
+		if err != nil {
+			s.option.Log.Error(errors.Wrapf(err, "failed to read stream %s", topic))
+			s.wait(ctx, time.Second)
+			continue
+		}
+
+		for _, st := range streams {
+			s.process(topic, st.Messages, callbacks)
+		}
+	}
+}
+
+func (s *stream) Read(topic string, callbacks []messaging.CallbackFunc) error {
+	return s.ReadWithContext(context.Background(), topic, callbacks)
+}
+
+func (s *stream) PublishWithContext(ctx context.Context, topic, message string) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	args := &redis.XAddArgs{
+		Stream: topic,
+		Values: map[string]interface{}{Field: message},
+	}
+
+	if s.option.MaxLen > 0 {
+		args.MaxLenApprox = s.option.MaxLen
+	}
+
+	if err := s.r.XAdd(args).Err(); err != nil {
+		s.option.Log.Error(err)
+		return errors.Wrapf(err, "failed to publish message on topic %s", topic)
+	}
+	return nil
+}
+
+func (s *stream) Publish(topic, message string) error {
+	return s.PublishWithContext(context.Background(), topic, message)
+}
+
+func (s *stream) AddTopicListener(topic string, callback messaging.CallbackFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.callbacks[topic] = append(s.callbacks[topic], callback)
+}
+
+// Listen starts consuming every topic registered with AddTopicListener.
+func (s *stream) Listen() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for topic, callbacks := range s.callbacks {
+		s.wg.Add(1)
+		go func(topic string, callbacks []messaging.CallbackFunc) {
+			defer s.wg.Done()
+
+			if err := s.ReadWithContext(s.ctx, topic, callbacks); err != nil && errors.Cause(err) != context.Canceled {
+				s.option.Log.Error(err)
+			}
+		}(topic, callbacks)
+	}
+}
+
+func (s *stream) Close() error {
+	s.cancel()
+	s.wg.Wait()
+
+	if err := s.client.Close(); err != nil {
+		return errors.Wrapf(err, "Failed to Close Redis Stream")
+	}
+	return nil
+}
+
+// createGroup creates the consumer group of topic from the beginning of the
+// stream so the entries published before the first read are not skipped.
+func (s *stream) createGroup(topic string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.groups[topic] {
+		return nil
+	}
+
+	err := s.r.XGroupCreateMkStream(topic, s.option.ConsumerGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return errors.Wrapf(err, "failed to create consumer group %s on stream %s", s.option.ConsumerGroup, topic)
+	}
+
+	s.groups[topic] = true
+	return nil
+}
+
+// claim takes over the entries left pending by other consumers for longer than ClaimMinIdle.
+func (s *stream) claim(topic string, callbacks []messaging.CallbackFunc) {
+	start := "0-0"
+	for {
+		cmd := redis.NewSliceCmd("xautoclaim", topic, s.option.ConsumerGroup, s.option.Consumer,
+			int64(s.option.ClaimMinIdle/time.Millisecond), start, "count", s.option.BatchSize)
+		_ = s.r.Process(cmd)
+
+		res, err := cmd.Result()
+		if err != nil {
+			s.option.Log.Error(errors.Wrapf(err, "failed to claim pending entries of stream %s", topic))
+			return
+		}
+
+		next, messages, err := parseAutoClaim(res)
+		if err != nil {
+			s.option.Log.Error(errors.Wrapf(err, "failed to claim pending entries of stream %s", topic))
+			return
+		}
+
+		s.process(topic, s.deliverable(topic, messages), callbacks)
+
+		if next == "0-0" {
+			return
+		}
+		start = next
+	}
+}
+
+func (s *stream) process(topic string, messages []redis.XMessage, callbacks []messaging.CallbackFunc) {
+	for _, m := range messages {
+		payload, _ := m.Values[Field].(string)
+
+		var failed bool
+		for _, c := range callbacks {
+			if err := c([]byte(payload)); err != nil {
+				s.option.Log.Error(err)
+				failed = true
+			}
+		}
+
+		if failed {
+			continue
+		}
+
+		if err := s.r.XAck(topic, s.option.ConsumerGroup, m.ID).Err(); err != nil {
+			s.option.Log.Error(errors.Wrapf(err, "failed to ack entry %s of stream %s", m.ID, topic))
+		}
+	}
+}
+
+// deliverable gives up the claimed entries delivered more than MaxDeliveries
+// times and returns the others.
+func (s *stream) deliverable(topic string, messages []redis.XMessage) []redis.XMessage {
+	if s.option.MaxDeliveries < 0 || len(messages) == 0 {
+		return messages
+	}
+
+	pipe := s.r.Pipeline()
+	cmds := make([]*redis.XPendingExtCmd, len(messages))
+	for i, m := range messages {
+		cmds[i] = pipe.XPendingExt(&redis.XPendingExtArgs{
+			Stream: topic,
+			Group:  s.option.ConsumerGroup,
+			Start:  m.ID,
+			End:    m.ID,
+			Count:  1,
+		})
+	}
+
+	if _, err := pipe.Exec(); err != nil {
+		s.option.Log.Error(errors.Wrapf(err, "failed to read deliveries of stream %s", topic))
+		return messages
+	}
+
+	kept := make([]redis.XMessage, 0, len(messages))
+	for i, m := range messages {
+		pending := cmds[i].Val()
+		if len(pending) == 0 || pending[0].RetryCount <= s.option.MaxDeliveries {
+			kept = append(kept, m)
+			continue
+		}
+
+		s.giveUp(topic, m, pending[0].RetryCount)
+	}
+
+	return kept
+}
+
+// giveUp acknowledges m once it is published to the dead letter stream, it
+// stays pending when the publish fails.
+func (s *stream) giveUp(topic string, m redis.XMessage, deliveries int64) {
+	s.option.Log.Errorf("giving up entry %s of stream %s after %d deliveries", m.ID, topic, deliveries)
+
+	if s.option.DeadLetter {
+		args := &redis.XAddArgs{Stream: messaging.DeadLetterTopic(topic), Values: m.Values}
+		if err := s.r.XAdd(args).Err(); err != nil {
+			s.option.Log.Error(errors.Wrapf(err, "failed to dead letter entry %s of stream %s", m.ID, topic))
+			return
+		}
+	}
+
+	if err := s.r.XAck(topic, s.option.ConsumerGroup, m.ID).Err(); err != nil {
+		s.option.Log.Error(errors.Wrapf(err, "failed to ack entry %s of stream %s", m.ID, topic))
+	}
+}
+
+func (s *stream) wait(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// parseAutoClaim reads the XAUTOCLAIM reply, entries deleted from the stream
+// while pending are returned as nil and skipped.
+func parseAutoClaim(reply []interface{}) (string, []redis.XMessage, error) {
+	if len(reply) < 2 {
+		return "", nil, errors.Errorf("unexpected xautoclaim reply %v", reply)
+	}
+
+	next, ok := reply[0].(string)
+	if !ok {
+		return "", nil, errors.Errorf("unexpected xautoclaim cursor %v", reply[0])
+	}
+
+	entries, ok := reply[1].([]interface{})
+	if !ok {
+		return "", nil, errors.Errorf("unexpected xautoclaim entries %v", reply[1])
+	}
+
+	messages := make([]redis.XMessage, 0, len(entries))
+	for _, e := range entries {
+		entry, ok := e.([]interface{})
+		if !ok || len(entry) < 2 {
+			continue
+		}
+
+		id, _ := entry[0].(string)
+		fields, _ := entry[1].([]interface{})
+
+		values := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			key, _ := fields[i].(string)
+			values[key] = fields[i+1]
+		}
+
+		messages = append(messages, redis.XMessage{ID: id, Values: values})
+	}
+
+	return next, messages, nil
+}
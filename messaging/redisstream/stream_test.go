@@ -0,0 +1,186 @@
+package redisstream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cacheredis "github.com/jajotz/utilities-golang/cache/redis"
+	"github.com/jajotz/utilities-golang/cache/redistest"
+	"github.com/jajotz/utilities-golang/messaging"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+func Test_parseAutoClaim_skips_deleted_entries(t *testing.T) {
+	next, messages, err := parseAutoClaim([]interface{}{
+		"1700000000000-1",
+		[]interface{}{
+			[]interface{}{"1700000000000-0", []interface{}{Field, "payload"}},
+			nil,
+		},
+		[]interface{}{"1699999999999-0"},
+	})
+
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if next != "1700000000000-1" {
+		t.Errorf("unexpected cursor %s", next)
+	}
+
+	if len(messages) != 1 || messages[0].ID != "1700000000000-0" || messages[0].Values[Field] != "payload" {
+		t.Errorf("unexpected messages %+v", messages)
+	}
+}
+
+func Test_parseAutoClaim_rejects_invalid_reply(t *testing.T) {
+	if _, _, err := parseAutoClaim([]interface{}{"0-0"}); err == nil {
+		t.Error("should error on invalid reply")
+	}
+}
+
+func newTestStream(t *testing.T, server *redistest.Server, consumer string) *stream {
+	s, err := newStream(&Option{
+		Redis:         &cacheredis.Option{Address: server.Addr()},
+		ConsumerGroup: "group",
+		Consumer:      consumer,
+		Block:         10 * time.Millisecond,
+		ClaimMinIdle:  time.Millisecond,
+		MaxDeliveries: 2,
+		DeadLetter:    true,
+	})
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	return s
+}
+
+func pendingCount(t *testing.T, s *stream, topic string) int64 {
+	pending, err := s.r.XPending(topic, s.option.ConsumerGroup).Result()
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	return pending.Count
+}
+
+// readOnce delivers the next entry of topic to the consumer of s without acknowledging it.
+func readOnce(t *testing.T, s *stream, topic string) {
+	if err := s.createGroup(topic); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if err := s.r.XReadGroup(&redis.XReadGroupArgs{
+		Group:    s.option.ConsumerGroup,
+		Consumer: s.option.Consumer,
+		Streams:  []string{topic, ">"},
+		Count:    1,
+		Block:    -1,
+	}).Err(); err != nil {
+		t.Fatal("should not error ", err)
+	}
+}
+
+func Test_Read_acks_processed_entries(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	s := newTestStream(t, server, "first")
+	defer s.Close()
+
+	if err := s.Publish("orders", "created"); err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var received string
+	err := s.ReadWithContext(ctx, "orders", []messaging.CallbackFunc{func(value []byte) error {
+		received = string(value)
+		cancel()
+		return nil
+	}})
+
+	if errors.Cause(err) != context.Canceled {
+		t.Error("expected canceled read, got ", err)
+	}
+
+	if received != "created" {
+		t.Errorf("unexpected message %s", received)
+	}
+
+	if count := pendingCount(t, s, "orders"); count != 0 {
+		t.Errorf("expected no pending entry, got %d", count)
+	}
+}
+
+func Test_claim_takes_over_idle_entries(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	first := newTestStream(t, server, "first")
+	defer first.Close()
+	second := newTestStream(t, server, "second")
+	defer second.Close()
+
+	_ = first.Publish("orders", "created")
+	readOnce(t, first, "orders")
+	time.Sleep(5 * time.Millisecond)
+
+	var received []string
+	second.claim("orders", []messaging.CallbackFunc{func(value []byte) error {
+		received = append(received, string(value))
+		return nil
+	}})
+
+	if len(received) != 1 || received[0] != "created" {
+		t.Errorf("unexpected messages %+v", received)
+	}
+
+	if count := pendingCount(t, second, "orders"); count != 0 {
+		t.Errorf("expected no pending entry, got %d", count)
+	}
+}
+
+func Test_claim_gives_up_after_MaxDeliveries(t *testing.T) {
+	server := redistest.Run(t)
+	defer server.Close()
+
+	s := newTestStream(t, server, "first")
+	defer s.Close()
+
+	_ = s.Publish("orders", "created")
+	readOnce(t, s, "orders")
+
+	var calls int
+	callbacks := []messaging.CallbackFunc{func(value []byte) error {
+		calls++
+		return errors.New("failed")
+	}}
+
+	// - the second delivery fails like the first one, the third one is given up
+	for i := 0; i < 2; i++ {
+		time.Sleep(5 * time.Millisecond)
+		s.claim("orders", callbacks)
+	}
+
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+
+	if count := pendingCount(t, s, "orders"); count != 0 {
+		t.Errorf("expected no pending entry, got %d", count)
+	}
+
+	entries, err := s.r.XRange(messaging.DeadLetterTopic("orders"), "-", "+").Result()
+	if err != nil {
+		t.Fatal("should not error ", err)
+	}
+
+	if len(entries) != 1 || entries[0].Values[Field] != "created" {
+		t.Errorf("unexpected dead letters %+v", entries)
+	}
+}
@@ -0,0 +1,205 @@
+package messaging
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderError             = "x-error"
+	// HeaderAttempt counts the attempts made on the message across the topics.
+	HeaderAttempt = "x-attempt"
+
+	retrySuffix      = ".retry."
+	deadLetterSuffix = ".dlq"
+)
+
+var ErrStopped = errors.New("retry stopped")
+
+type (
+	// RetryPolicy retries a failed message up to MaxAttempts times, including the
+	// first one, waiting Backoff doubled after every attempt up to MaxBackoff.
+	RetryPolicy struct {
+		MaxAttempts int
+		Backoff     time.Duration
+		MaxBackoff  time.Duration
+	}
+
+	// DeadLetter routes the messages still failing once the RetryPolicy gave up
+	// through RetryTopics topics <topic>.retry.N, consumed with the callbacks of
+	// <topic>, and finally to <topic>.dlq where they wait to be replayed.
+	// Every callback of <topic> runs again on a retry topic or after a replay,
+	// including the ones that already succeeded, so they have to be idempotent.
+	DeadLetter struct {
+		Enabled     bool
+		RetryTopics int
+		// RetryDelay is how long a message waits in <topic>.retry.1 before being
+		// processed again, it is doubled on every following retry topic.
+		RetryDelay time.Duration
+	}
+
+	// Failure is a message whose callbacks failed on every attempt.
+	Failure struct {
+		Topic     string
+		Partition int32
+		Offset    int64
+		// Headers are the headers the message was received with.
+		Headers  map[string]string
+		Attempts int
+		Err      error
+	}
+
+	// Replayer is implemented by the queues supporting DeadLetter, it republishes
+	// up to limit messages of the <topic>.dlq topic to their source topic and
+	// returns how many were replayed, a limit of 0 replays them all.
+	Replayer interface {
+		ReplayWithContext(ctx context.Context, topic string, limit int) (int, error)
+		Replay(topic string, limit int) (int, error)
+	}
+)
+
+// Run runs the callbacks with value until they all succeeded, only the callbacks
+// that failed are run again. It returns the attempts made and the last error,
+// ErrStopped when stop is closed while waiting for the next attempt.
+func (p RetryPolicy) Run(stop <-chan struct{}, value []byte, callbacks []CallbackFunc) (int, error) {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		failed := make([]CallbackFunc, 0)
+		var err error
+		for _, function := range callbacks {
+			if e := function(value); e != nil {
+				failed = append(failed, function)
+				err = e
+			}
+		}
+
+		if len(failed) == 0 {
+			return attempt, nil
+		}
+
+		if attempt >= p.MaxAttempts {
+			return attempt, err
+		}
+
+		select {
+		case <-stop:
+			return attempt, ErrStopped
+		case <-time.After(backoff):
+		}
+
+		callbacks = failed
+		if backoff *= 2; p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+func RetryTopic(topic string, n int) string {
+	return topic + retrySuffix + strconv.Itoa(n)
+}
+
+func DeadLetterTopic(topic string) string {
+	return topic + deadLetterSuffix
+}
+
+// ParseTopic returns the source topic of a retry or dead letter topic, retry is
+// the number of a retry topic and 0 otherwise.
+func ParseTopic(topic string) (source string, retry int) {
+	if strings.HasSuffix(topic, deadLetterSuffix) {
+		return strings.TrimSuffix(topic, deadLetterSuffix), 0
+	}
+
+	if i := strings.LastIndex(topic, retrySuffix); i > 0 {
+		if n, err := strconv.Atoi(topic[i+len(retrySuffix):]); err == nil && n > 0 {
+			return topic[:i], n
+		}
+	}
+
+	return topic, 0
+}
+
+// Topics returns the retry topics to consume along with topic.
+func (d DeadLetter) Topics(topic string) []string {
+	if !d.Enabled {
+		return nil
+	}
+
+	topics := make([]string, 0, d.RetryTopics)
+	for n := 1; n <= d.RetryTopics; n++ {
+		topics = append(topics, RetryTopic(topic, n))
+	}
+	return topics
+}
+
+// Delay returns how long the messages of topic wait before being processed.
+func (d DeadLetter) Delay(topic string) time.Duration {
+	_, n := ParseTopic(topic)
+	if !d.Enabled || n == 0 {
+		return 0
+	}
+
+	return d.RetryDelay << uint(n-1)
+}
+
+// Route returns the topic f is published to with its headers, the next retry
+// topic or the dead letter topic once the retry topics are exhausted. The origin
+// of a message coming from a retry topic is kept. The topic is empty when dead
+// letters are disabled.
+func (d DeadLetter) Route(f *Failure) (topic string, headers map[string]string) {
+	if !d.Enabled {
+		return "", nil
+	}
+
+	source, n := ParseTopic(f.Topic)
+	if n < d.RetryTopics {
+		topic = RetryTopic(source, n+1)
+	} else {
+		topic = DeadLetterTopic(source)
+	}
+
+	headers = make(map[string]string, len(f.Headers)+5)
+	for k, v := range f.Headers {
+		headers[k] = v
+	}
+
+	if _, ok := headers[HeaderOriginalTopic]; !ok {
+		headers[HeaderOriginalTopic] = f.Topic
+		headers[HeaderOriginalPartition] = strconv.Itoa(int(f.Partition))
+		headers[HeaderOriginalOffset] = strconv.FormatInt(f.Offset, 10)
+	}
+
+	attempts, _ := strconv.Atoi(headers[HeaderAttempt])
+	headers[HeaderAttempt] = strconv.Itoa(attempts + f.Attempts)
+	if f.Err != nil {
+		headers[HeaderError] = f.Err.Error()
+	}
+
+	return topic, headers
+}
+
+// Replay returns the topic a dead letter is republished to and its headers
+// without the failure ones, so it is processed as a new message.
+func Replay(topic string, headers map[string]string) (string, map[string]string) {
+	target, _ := ParseTopic(topic)
+	if original := headers[HeaderOriginalTopic]; original != "" {
+		target = original
+	}
+
+	kept := make(map[string]string, len(headers))
+	for k, v := range headers {
+		switch k {
+		case HeaderOriginalTopic, HeaderOriginalPartition, HeaderOriginalOffset, HeaderError, HeaderAttempt:
+		default:
+			kept[k] = v
+		}
+	}
+
+	return target, kept
+}
@@ -0,0 +1,79 @@
+package messaging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func Test_ParseTopic(t *testing.T) {
+	cases := map[string]struct {
+		source string
+		retry  int
+	}{
+		"orders":             {"orders", 0},
+		"orders.retry.2":     {"orders", 2},
+		"orders.dlq":         {"orders", 0},
+		"orders.retry.later": {"orders.retry.later", 0},
+	}
+
+	for topic, expected := range cases {
+		if source, retry := ParseTopic(topic); source != expected.source || retry != expected.retry {
+			t.Errorf("expected %s to parse as %v, got %s %d", topic, expected, source, retry)
+		}
+	}
+}
+
+func Test_DeadLetter_Route_through_retry_topics(t *testing.T) {
+	d := DeadLetter{Enabled: true, RetryTopics: 2, RetryDelay: time.Second}
+
+	topic, headers := d.Route(&Failure{Topic: "orders", Partition: 3, Offset: 42, Attempts: 3, Err: errors.New("boom")})
+	if topic != "orders.retry.1" {
+		t.Errorf("expected orders.retry.1, got %s", topic)
+	}
+
+	if headers[HeaderOriginalTopic] != "orders" || headers[HeaderOriginalPartition] != "3" || headers[HeaderOriginalOffset] != "42" ||
+		headers[HeaderError] != "boom" || headers[HeaderAttempt] != "3" {
+		t.Errorf("unexpected headers %v", headers)
+	}
+
+	topic, headers = d.Route(&Failure{Topic: "orders.retry.2", Partition: 0, Offset: 7, Headers: headers, Attempts: 3, Err: errors.New("still")})
+	if topic != "orders.dlq" {
+		t.Errorf("expected orders.dlq, got %s", topic)
+	}
+
+	if headers[HeaderOriginalPartition] != "3" || headers[HeaderOriginalOffset] != "42" || headers[HeaderError] != "still" || headers[HeaderAttempt] != "6" {
+		t.Errorf("expected the origin to be kept, got %v", headers)
+	}
+
+	if delay := d.Delay("orders.retry.2"); delay != 2*time.Second {
+		t.Errorf("expected a 2s delay, got %s", delay)
+	}
+}
+
+func Test_Replay_strips_failure_headers(t *testing.T) {
+	topic, headers := Replay("orders.dlq", map[string]string{
+		HeaderOriginalTopic: "orders",
+		HeaderError:         "boom",
+		HeaderAttempt:       "6",
+		"trace-id":          "abc",
+	})
+
+	if topic != "orders" || len(headers) != 1 || headers["trace-id"] != "abc" {
+		t.Errorf("unexpected replay %s %v", topic, headers)
+	}
+}
+
+func Test_RetryPolicy_Run_gives_up(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	calls := 0
+	attempts, err := p.Run(nil, nil, []CallbackFunc{func([]byte) error {
+		calls++
+		return errors.New("boom")
+	}})
+
+	if err == nil || attempts != 3 || calls != 3 {
+		t.Errorf("expected 3 failed attempts, got %d calls %d %v", calls, attempts, err)
+	}
+}
@@ -45,6 +45,8 @@ const (
 	TOO_MANY_REQUEST           = "TOO_MANY_REQUEST"
 	BAD_REQUEST                = "BAD_REQUEST"
 	UNAUTHORIZE                = "UNAUTHORIZE"
+	REQUEST_IN_PROGRESS        = "REQUEST_IN_PROGRESS"
+	IDEMPOTENCY_KEY_REUSED     = "IDEMPOTENCY_KEY_REUSED"
 )
 
 var (
@@ -96,8 +98,8 @@ var (
 		},
 		TOO_MANY_REQUEST: ResponseCode{
 			code:       TOO_MANY_REQUEST,
-			message:    "Invalid data",
-			httpStatus: http.StatusOK,
+			message:    "Too many request",
+			httpStatus: http.StatusTooManyRequests,
 		},
 		BAD_REQUEST: ResponseCode{
 			code:       BAD_REQUEST,
@@ -109,6 +111,16 @@ var (
 			message:    "Unauthorize",
 			httpStatus: http.StatusUnauthorized,
 		},
+		REQUEST_IN_PROGRESS: ResponseCode{
+			code:       REQUEST_IN_PROGRESS,
+			message:    "Request is already in progress",
+			httpStatus: http.StatusConflict,
+		},
+		IDEMPOTENCY_KEY_REUSED: ResponseCode{
+			code:       IDEMPOTENCY_KEY_REUSED,
+			message:    "Idempotency key was used for another request",
+			httpStatus: http.StatusUnprocessableEntity,
+		},
 	}
 )
 